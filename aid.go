@@ -3,34 +3,245 @@
 
 package iso7816
 
-func concat(prefix []byte, rest ...byte) (r []byte) {
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+const (
+	MaxLenAID = 16
+
+	lenRID = 5
+)
+
+var ErrInvalidAID = errors.New("invalid application identifier")
+
+var (
+	_ fmt.Stringer             = AID{}
+	_ encoding.TextMarshaler   = AID{}
+	_ encoding.TextUnmarshaler = (*AID)(nil)
+)
+
+func concat(prefix []byte, rest ...byte) (r AID) {
 	r = append(r, prefix...)
 	return append(r, rest...)
 }
 
-type RID [5]byte
+// AIDCategory is the registration category indicated
+// by the first hexadecimal digit of an AID.
+// See: ISO 7816-5
+type AIDCategory byte
 
-func (r RID) String() string {
-	switch r {
-	case RidNIST:
-		return "NIST"
-	case RidFSFE:
-		return "FSFE"
-	case RidYubico:
-		return "Yubico"
-	case RidFIDO:
-		return "FIDO"
-	case RidSolokeys:
-		return "Solokeys"
-	case RidGlobalPlatform:
-		return "GlobalPlatform"
-	case RidNXPNFC:
-		return "NXP NFC"
+const (
+	AIDCategoryInternational AIDCategory = 0xA // International registration of the RID
+	AIDCategoryNational      AIDCategory = 0xD // National registration of the RID
+	AIDCategoryStandard      AIDCategory = 0xE // Standard, identified by an object identifier
+	AIDCategoryProprietary   AIDCategory = 0xF // Proprietary, not registered
+)
+
+func (c AIDCategory) String() string {
+	switch c {
+	case AIDCategoryInternational:
+		return "international"
+	case AIDCategoryNational:
+		return "national"
+	case AIDCategoryStandard:
+		return "standard"
+	case AIDCategoryProprietary:
+		return "proprietary"
 	default:
 		return "<unknown>"
 	}
 }
 
+// AID is an application identifier consisting of a
+// registered application provider identifier (RID) and
+// a proprietary application identifier extension (PIX).
+// See: ISO 7816-4 Section 8.2.1.2 Application identifier
+type AID []byte
+
+// ParseAID parses the hexadecimal representation of an AID.
+func ParseAID(s string) (AID, error) {
+	var a AID
+	if err := a.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Category returns the registration category of the AID.
+func (a AID) Category() AIDCategory {
+	if len(a) == 0 {
+		return 0
+	}
+
+	return AIDCategory(a[0] >> 4)
+}
+
+// RID returns the registered application provider identifier.
+// Only AIDs of the international and national categories have a RID.
+func (a AID) RID() (RID, bool) {
+	if len(a) < lenRID {
+		return RID{}, false
+	}
+
+	if c := a.Category(); c != AIDCategoryInternational && c != AIDCategoryNational {
+		return RID{}, false
+	}
+
+	return RID(a[:lenRID]), true
+}
+
+// PIX returns the proprietary application identifier extension
+// following the RID or nil if the AID has no RID.
+func (a AID) PIX() []byte {
+	if _, ok := a.RID(); !ok {
+		return nil
+	}
+
+	return a[lenRID:]
+}
+
+// HasPrefix checks if the AID starts with the given partial AID.
+// This corresponds to the selection by partial DF name.
+func (a AID) HasPrefix(prefix AID) bool {
+	return bytes.HasPrefix(a, prefix)
+}
+
+// Equal checks if both AIDs are identical.
+func (a AID) Equal(b AID) bool {
+	return bytes.Equal(a, b)
+}
+
+// Applet returns the known applet with the longest AID which is a prefix of the AID.
+func (a AID) Applet() (KnownApplet, bool) {
+	var (
+		applet KnownApplet
+		found  bool
+	)
+
+	for _, app := range KnownApplets {
+		if len(app.AID) > len(applet.AID) && a.HasPrefix(app.AID) {
+			applet, found = app, true
+		}
+	}
+
+	return applet, found
+}
+
+// Name returns a human readable name of the AID.
+// It is the name of the known applet, the decoded OpenPGP AID or
+// the name of the application provider. An empty string is returned
+// if the AID is not found in the registry.
+func (a AID) Name() string {
+	if o, ok := a.OpenPGP(); ok {
+		return o.String()
+	}
+
+	if app, ok := a.Applet(); ok {
+		return app.Name
+	}
+
+	if rid, ok := a.RID(); ok {
+		if name, ok := rid.Name(); ok {
+			return name + " application"
+		}
+	}
+
+	return ""
+}
+
+// String returns the AID in upper-case hexadecimal representation.
+func (a AID) String() string {
+	return fmt.Sprintf("%X", []byte(a))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a AID) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *AID) UnmarshalText(text []byte) error {
+	b := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(b, text); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAID, err)
+	}
+
+	if len(b) == 0 || len(b) > MaxLenAID {
+		return fmt.Errorf("%w: length must be between 1 and %d bytes, got %d", ErrInvalidAID, MaxLenAID, len(b))
+	}
+
+	*a = b
+
+	return nil
+}
+
+// OpenPGP decodes the PIX of a full-length AID of the OpenPGP card application.
+// See: https://gnupg.org/ftp/specs/OpenPGP-smart-card-application-3.4.1.pdf Section 4.2.1
+func (a AID) OpenPGP() (*OpenPGPAID, bool) {
+	if len(a) != MaxLenAID || !a.HasPrefix(AidOpenPGP) {
+		return nil, false
+	}
+
+	return &OpenPGPAID{
+		Version:      [2]byte{a[6], a[7]},
+		Manufacturer: binary.BigEndian.Uint16(a[8:10]),
+		Serial:       binary.BigEndian.Uint32(a[10:14]),
+	}, true
+}
+
+// OpenPGPAID contains the fields of the PIX of the OpenPGP card application.
+type OpenPGPAID struct {
+	Version      [2]byte // Major and minor version of the specification
+	Manufacturer uint16
+	Serial       uint32
+}
+
+// ManufacturerName returns the name of the manufacturer
+// or an empty string if the manufacturer is unknown.
+func (o OpenPGPAID) ManufacturerName() string {
+	if o.Manufacturer >= 0xFF00 && o.Manufacturer != 0xFFFF {
+		return "Unmanaged S/N range"
+	}
+
+	return openPGPManufacturers[o.Manufacturer]
+}
+
+func (o OpenPGPAID) String() string {
+	mf := o.ManufacturerName()
+	if mf == "" {
+		mf = fmt.Sprintf("manufacturer %04X", o.Manufacturer)
+	}
+
+	return fmt.Sprintf("OpenPGP %d.%d (%s, serial %08X)", o.Version[0], o.Version[1], mf, o.Serial)
+}
+
+// RID is a registered application provider identifier.
+type RID [lenRID]byte
+
+// Name returns the name of the application provider
+// if the RID is found in the registry.
+func (r RID) Name() (string, bool) {
+	name, ok := registeredRIDs[r]
+	return name, ok
+}
+
+// String returns the name of the application provider or
+// the hexadecimal representation of the RID if it is unknown.
+func (r RID) String() string {
+	if name, ok := r.Name(); ok {
+		return name
+	}
+
+	return fmt.Sprintf("%X", r[:])
+}
+
 //nolint:gochecknoglobals
 var (
 	// https://www.eftlab.com/knowledge-base/complete-list-of-registered-application-provider-identifiers-rid
@@ -41,8 +252,69 @@ var (
 	RidSolokeys       = RID{0xA0, 0x00, 0x00, 0x08, 0x47}
 	RidGlobalPlatform = RID{0xA0, 0x00, 0x00, 0x01, 0x51}
 	RidNXPNFC         = RID{0xD2, 0x76, 0x00, 0x00, 0x85}
+	RidVisa           = RID{0xA0, 0x00, 0x00, 0x00, 0x03}
+	RidMastercard     = RID{0xA0, 0x00, 0x00, 0x00, 0x04}
+	RidAmex           = RID{0xA0, 0x00, 0x00, 0x00, 0x25}
+	RidICAO           = RID{0xA0, 0x00, 0x00, 0x02, 0x47}
+	RidETSI3GPP       = RID{0xA0, 0x00, 0x00, 0x00, 0x87}
+	RidMicrosoft      = RID{0xA0, 0x00, 0x00, 0x03, 0x97}
 )
 
+//nolint:gochecknoglobals
+var registeredRIDs = map[RID]string{
+	RidNIST:           "NIST",
+	RidFSFE:           "FSFE",
+	RidYubico:         "Yubico",
+	RidFIDO:           "FIDO",
+	RidSolokeys:       "Solokeys",
+	RidGlobalPlatform: "GlobalPlatform",
+	RidNXPNFC:         "NXP NFC",
+	RidVisa:           "Visa",
+	RidMastercard:     "Mastercard",
+	RidAmex:           "American Express",
+	RidICAO:           "ICAO",
+	RidETSI3GPP:       "ETSI 3GPP",
+	RidMicrosoft:      "Microsoft",
+
+	{0xA0, 0x00, 0x00, 0x00, 0x09}: "ETSI",
+	{0xA0, 0x00, 0x00, 0x00, 0x18}: "Gemplus",
+	{0xA0, 0x00, 0x00, 0x00, 0x42}: "Cartes Bancaires",
+	{0xA0, 0x00, 0x00, 0x00, 0x62}: "Sun Microsystems",
+	{0xA0, 0x00, 0x00, 0x00, 0x63}: "RSA Laboratories",
+	{0xA0, 0x00, 0x00, 0x00, 0x65}: "JCB",
+	{0xA0, 0x00, 0x00, 0x00, 0x77}: "Oberthur Technologies",
+	{0xA0, 0x00, 0x00, 0x00, 0x79}: "US Department of Defense",
+	{0xA0, 0x00, 0x00, 0x01, 0x16}: "US General Services Administration",
+	{0xA0, 0x00, 0x00, 0x01, 0x52}: "Discover",
+	{0xA0, 0x00, 0x00, 0x01, 0x67}: "IBM",
+	{0xA0, 0x00, 0x00, 0x02, 0x77}: "Interac",
+	{0xA0, 0x00, 0x00, 0x03, 0x33}: "China UnionPay",
+	{0xA0, 0x00, 0x00, 0x05, 0x24}: "RuPay",
+}
+
+// Manufacturer identifiers of OpenPGP cards
+// See: https://github.com/gpg/gnupg/blob/master/scd/app-openpgp.c
+//
+//nolint:gochecknoglobals
+var openPGPManufacturers = map[uint16]string{
+	0x0001: "PPC Card Systems",
+	0x0002: "Prism",
+	0x0003: "OpenFortress",
+	0x0004: "Wewid",
+	0x0005: "ZeitControl",
+	0x0006: "Yubico",
+	0x0007: "OpenKMS",
+	0x0008: "LogoEmail",
+	0x0009: "Fidesmo",
+	0x000F: "Nitrokey",
+	0x002A: "Magrathea",
+	0x0042: "GnuPG e.V.",
+	0x2342: "warpzone",
+	0xF517: "FSIJ",
+	0x0000: "test card",
+	0xFFFF: "test card",
+}
+
 //nolint:gochecknoglobals
 var (
 	// https://nvlpubs.nist.gov/nistpubs/specialpublications/nist.sp.800-73-4.pdf
@@ -65,5 +337,58 @@ var (
 	AidNDEF                = concat(RidNXPNFC[:], 0x01, 0x01)
 
 	// Feitian seems to use an unregistered RID?
-	AidFeitianOTP = []byte{0xD1, 0x56, 0x00, 0x01, 0x32, 0x83, 0x26, 0x01, 0x01}
+	AidFeitianOTP = AID{0xD1, 0x56, 0x00, 0x01, 0x32, 0x83, 0x26, 0x01, 0x01}
 )
+
+// KnownApplet is a well-known applet which can be probed by its AID.
+type KnownApplet struct {
+	Name string
+	AID  AID
+}
+
+// KnownApplets is a registry of well-known applets which is used for naming
+// AIDs and for probing the applets of a card by Card.Discover().
+//
+//nolint:gochecknoglobals
+var KnownApplets = []KnownApplet{
+	{"PIV", AidPIV},
+	{"OpenPGP", AidOpenPGP},
+	{"FIDO U2F/CTAP2", AidFIDO},
+	{"Yubico OTP", AidYubicoOTP},
+	{"Yubico Management", AidYubicoManagement},
+	{"Yubico OATH", AidYubicoOATH},
+	{"Yubico HSM Auth", AidYubicoHSMAuth},
+	{"Solokeys Admin", AidSolokeysAdmin},
+	{"Solokeys Provisioner", AidSolokeysProvisioner},
+	{"GlobalPlatform Card Manager", AidCardManager},
+	{"NFC Forum NDEF", AidNDEF},
+	{"Feitian OTP", AidFeitianOTP},
+	{"Microsoft GIDS", concat(RidMicrosoft[:], 0x42, 0x54, 0x46, 0x59, 0x02, 0x01)},
+
+	// https://www.eftlab.com/knowledge-base/complete-list-of-application-identifiers-aid
+	{"Visa Credit/Debit", concat(RidVisa[:], 0x10, 0x10)},
+	{"Visa Electron", concat(RidVisa[:], 0x20, 0x10)},
+	{"V PAY", concat(RidVisa[:], 0x20, 0x20)},
+	{"Visa Plus", concat(RidVisa[:], 0x80, 0x10)},
+	{"Mastercard Credit/Debit", concat(RidMastercard[:], 0x10, 0x10)},
+	{"Maestro", concat(RidMastercard[:], 0x30, 0x60)},
+	{"Cirrus", concat(RidMastercard[:], 0x60, 0x00)},
+	{"American Express", concat(RidAmex[:], 0x01)},
+	{"Cartes Bancaires", AID{0xA0, 0x00, 0x00, 0x00, 0x42, 0x10, 0x10}},
+	{"Discover", AID{0xA0, 0x00, 0x00, 0x01, 0x52, 0x30, 0x10}},
+	{"JCB", AID{0xA0, 0x00, 0x00, 0x00, 0x65, 0x10, 0x10}},
+	{"Interac", AID{0xA0, 0x00, 0x00, 0x02, 0x77, 0x10, 0x10}},
+	{"UnionPay Debit", AID{0xA0, 0x00, 0x00, 0x03, 0x33, 0x01, 0x01, 0x01}},
+	{"RuPay", AID{0xA0, 0x00, 0x00, 0x05, 0x24, 0x10, 0x10}},
+	{"EMV Payment System Environment", AID("1PAY.SYS.DDF01")},
+	{"EMV Proximity Payment System Environment", AID("2PAY.SYS.DDF01")},
+	{"Visa Open Platform Card Manager", concat(RidVisa[:], 0x00, 0x00, 0x00)},
+
+	{"3GPP USIM", concat(RidETSI3GPP[:], 0x10, 0x02)},
+	{"3GPP ISIM", concat(RidETSI3GPP[:], 0x10, 0x04)},
+
+	{"ICAO eMRTD LDS1", concat(RidICAO[:], 0x10, 0x01)},
+	{"German eID (nPA)", AID{0xE8, 0x07, 0x04, 0x00, 0x7F, 0x00, 0x07, 0x03, 0x02}},
+	{"German eSign", AID{0xA0, 0x00, 0x00, 0x01, 0x67, 0x45, 0x53, 0x49, 0x47, 0x4E}},
+	{"PKCS #15", AID{0xA0, 0x00, 0x00, 0x00, 0x63, 0x50, 0x4B, 0x43, 0x53, 0x2D, 0x31, 0x35}},
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+func TestAID(t *testing.T) {
+	require := require.New(t)
+
+	aid, err := iso.ParseAID("a000000308000010000100")
+	require.NoError(err)
+	require.Equal(iso.AIDCategoryInternational, aid.Category())
+	require.Equal(unhex("000010000100"), aid.PIX())
+	require.True(aid.HasPrefix(iso.AidPIV))
+	require.Equal("PIV", aid.Name())
+	require.Equal("A000000308000010000100", aid.String())
+
+	rid, ok := aid.RID()
+	require.True(ok)
+	require.Equal(iso.RidNIST, rid)
+	require.Equal("NIST", rid.String())
+
+	require.Equal("Yubico application", iso.AID(unhex("a000000527ffff")).Name())
+	require.Equal("A000001234", iso.RID(unhex("a000001234")).String())
+
+	nPA := iso.AID(unhex("e80704007f00070302"))
+	require.Equal(iso.AIDCategoryStandard, nPA.Category())
+	require.Nil(nPA.PIX())
+	require.Equal("German eID (nPA)", nPA.Name())
+
+	_, ok = nPA.RID()
+	require.False(ok)
+
+	_, err = iso.ParseAID("a0")
+	require.NoError(err)
+
+	for _, s := range []string{"", "a0000", "zz", "a0000003080000100001000000000000ff"} {
+		_, err = iso.ParseAID(s)
+		require.ErrorIs(err, iso.ErrInvalidAID, s)
+	}
+}
+
+func TestAIDText(t *testing.T) {
+	require := require.New(t)
+
+	b, err := json.Marshal(map[string]iso.AID{"aid": iso.AidOpenPGP})
+	require.NoError(err)
+	require.JSONEq(`{"aid":"D27600012401"}`, string(b))
+
+	var m map[string]iso.AID
+	err = json.Unmarshal(b, &m)
+	require.NoError(err)
+	require.Equal(iso.AidOpenPGP, m["aid"])
+}
+
+func TestAIDOpenPGP(t *testing.T) {
+	require := require.New(t)
+
+	aid := iso.AID(unhex("d2760001240103040006123456780000"))
+
+	o, ok := aid.OpenPGP()
+	require.True(ok)
+	require.Equal([2]byte{3, 4}, o.Version)
+	require.Equal(uint16(0x0006), o.Manufacturer)
+	require.Equal(uint32(0x12345678), o.Serial)
+	require.Equal("OpenPGP 3.4 (Yubico, serial 12345678)", aid.Name())
+
+	_, ok = iso.AidOpenPGP.OpenPGP()
+	require.False(ok)
+	require.Equal("OpenPGP", iso.AidOpenPGP.Name())
+}
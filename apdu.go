@@ -24,13 +24,18 @@ const (
 )
 
 type CAPDU struct {
-	Cla    byte        // Cla is the class byte.
+	Cla    Class       // Cla is the class byte.
 	Ins    Instruction // Ins is the instruction byte.
 	P1, P2 byte        // P1, P2 is the p1, p2 byte.
 	Data   []byte      // Data is the data field.
 	Ne     int         // Ne is the total number of expected response data byte (not LE encoded).
 }
 
+// isExtended returns true if the CAPDU requires extended length fields.
+func (c *CAPDU) isExtended() bool {
+	return len(c.Data) > MaxLenCommandDataStandard || c.Ne > MaxLenResponseDataStandard
+}
+
 func (c *CAPDU) Bytes() ([]byte, error) {
 	if len(c.Data) > MaxLenCommandDataExtended {
 		return nil, fmt.Errorf("%w:CAPDU data length %d exceeds maximum allowed length of %d",
@@ -44,7 +49,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 
 	switch {
 	case len(c.Data) == 0 && c.Ne == 0: // Case 1: Cla | Ins | P1 | P2
-		return []byte{c.Cla, byte(c.Ins), c.P1, c.P2}, nil
+		return []byte{byte(c.Cla), byte(c.Ins), c.P1, c.P2}, nil
 
 	case len(c.Data) == 0 && c.Ne > 0: // Case 2
 		// Extended format: Cla | Ins | P1 | P2 | Le (extended)
@@ -60,7 +65,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 			}
 
 			result := make([]byte, 0, LenHeader+LenLCExtended)
-			result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2)
+			result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2)
 			result = append(result, le...)
 
 			return result, nil
@@ -68,7 +73,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 
 		// Standard format: Cla | Ins | P1 | P2 | Le
 		result := make([]byte, 0, LenHeader+LenLCStandard)
-		result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2)
+		result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2)
 
 		if c.Ne == MaxLenResponseDataStandard {
 			result = append(result, 0x00)
@@ -86,7 +91,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 			lc[2] = (byte)(len(c.Data) & 0xFF)
 
 			result := make([]byte, 0, LenHeader+LenLCExtended+len(c.Data))
-			result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2)
+			result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2)
 			result = append(result, lc...)
 			result = append(result, c.Data...)
 
@@ -95,7 +100,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 
 		// Standard format: Cla | Ins | P1 | P2 | Lc | Data
 		result := make([]byte, 0, LenHeader+1+len(c.Data))
-		result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2, byte(len(c.Data)))
+		result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2, byte(len(c.Data)))
 		result = append(result, c.Data...)
 
 		return result, nil
@@ -116,7 +121,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 		}
 
 		result := make([]byte, 0, LenHeader+LenLCExtended+len(c.Data)+len(le))
-		result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2)
+		result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2)
 		result = append(result, lc...)
 		result = append(result, c.Data...)
 		result = append(result, le...)
@@ -125,7 +130,7 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 
 	default: // Standard format: Cla | Ins | P1 | P2 | Lc | Data | Ne
 		result := make([]byte, 0, LenHeader+LenLCStandard+len(c.Data)+1)
-		result = append(result, c.Cla, byte(c.Ins), c.P1, c.P2, byte(len(c.Data)))
+		result = append(result, byte(c.Cla), byte(c.Ins), c.P1, c.P2, byte(len(c.Data)))
 		result = append(result, c.Data...)
 		result = append(result, byte(c.Ne))
 
@@ -133,6 +138,83 @@ func (c *CAPDU) Bytes() ([]byte, error) {
 	}
 }
 
+// ParseCAPDU parses a Command APDU in short or extended length format and returns a CAPDU.
+// See: ISO 7816-4 Section 5.1 Command-response pairs
+func ParseCAPDU(b []byte) (*CAPDU, error) {
+	if len(b) < LenHeader {
+		return nil, fmt.Errorf("%w: a CAPDU must consist of at least %d byte, got %d", errInvalidLength, LenHeader, len(b))
+	}
+
+	c := &CAPDU{
+		Cla: Class(b[0]),
+		Ins: Instruction(b[1]),
+		P1:  b[2],
+		P2:  b[3],
+	}
+
+	body := b[LenHeader:]
+
+	switch {
+	case len(body) == 0: // Case 1: Cla | Ins | P1 | P2
+		return c, nil
+
+	case len(body) == 1: // Case 2 standard: Cla | Ins | P1 | P2 | Le
+		c.Ne = decodeLe(body)
+
+	case body[0] != 0x00: // Case 3/4 standard: Cla | Ins | P1 | P2 | Lc | Data [| Le]
+		lc := int(body[0])
+
+		switch len(body) {
+		case LenLCStandard + lc:
+		case LenLCStandard + lc + 1:
+			c.Ne = decodeLe(body[LenLCStandard+lc:])
+		default:
+			return nil, fmt.Errorf("%w: Lc %d does not match body length %d", errInvalidLength, lc, len(body))
+		}
+
+		c.Data = body[LenLCStandard : LenLCStandard+lc]
+
+	case len(body) == LenLCExtended: // Case 2 extended: Cla | Ins | P1 | P2 | Le (extended)
+		c.Ne = decodeLe(body[1:])
+
+	case len(body) > LenLCExtended: // Case 3/4 extended: Cla | Ins | P1 | P2 | Lc (extended) | Data [| Le (extended)]
+		lc := int(body[1])<<8 | int(body[2])
+		if lc == 0 {
+			return nil, fmt.Errorf("%w: extended Lc must not be zero", errInvalidLength)
+		}
+
+		switch len(body) {
+		case LenLCExtended + lc:
+		case LenLCExtended + lc + 2:
+			c.Ne = decodeLe(body[LenLCExtended+lc:])
+		default:
+			return nil, fmt.Errorf("%w: Lc %d does not match body length %d", errInvalidLength, lc, len(body))
+		}
+
+		c.Data = body[LenLCExtended : LenLCExtended+lc]
+
+	default:
+		return nil, fmt.Errorf("%w: invalid body length %d", errInvalidLength, len(body))
+	}
+
+	return c, nil
+}
+
+// decodeLe decodes a short or extended Le field.
+// An Le field of only zero bytes encodes the maximum length.
+func decodeLe(le []byte) int {
+	ne := 0
+	for _, b := range le {
+		ne = ne<<8 | int(b)
+	}
+
+	if ne == 0 {
+		return 1 << (8 * len(le))
+	}
+
+	return ne
+}
+
 type RAPDU struct {
 	Data     []byte
 	SW1, SW2 byte
@@ -151,6 +233,20 @@ func ParseRAPDU(b []byte) (*RAPDU, error) {
 	return &RAPDU{Data: b[:len(b)-LenResponseTrailer], SW1: b[len(b)-2], SW2: b[len(b)-1]}, nil
 }
 
+// Bytes serializes the RAPDU.
+func (r *RAPDU) Bytes() ([]byte, error) {
+	if len(r.Data) > MaxLenResponseDataExtended {
+		return nil, fmt.Errorf("%w: RAPDU data length %d exceeds maximum allowed length of %d",
+			errInvalidLength, len(r.Data), MaxLenResponseDataExtended)
+	}
+
+	b := make([]byte, 0, len(r.Data)+LenResponseTrailer)
+	b = append(b, r.Data...)
+	b = append(b, r.SW1, r.SW2)
+
+	return b, nil
+}
+
 // IsSuccess returns true if the RAPDU indicates the successful execution of a command ('0x61xx' or '0x9000'), otherwise false.
 func (r *RAPDU) IsSuccess() bool {
 	return r.SW1 == 0x61 || r.SW1 == 0x90 && r.SW2 == 0x00
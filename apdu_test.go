@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+func TestParseCAPDU(t *testing.T) {
+	for n, c := range map[string]iso.CAPDU{
+		"case 1":            {Cla: 0x00, Ins: 0xA4, P1: 0x04, P2: 0x00},
+		"case 2 standard":   {Ins: iso.InsGetChallenge, Ne: 8},
+		"case 2 standard 0": {Ins: iso.InsGetChallenge, Ne: iso.MaxLenResponseDataStandard},
+		"case 2 extended":   {Ins: iso.InsGetChallenge, Ne: 1000},
+		"case 2 extended 0": {Ins: iso.InsGetChallenge, Ne: iso.MaxLenResponseDataExtended},
+		"case 3 standard":   {Ins: iso.InsSelect, P1: 0x04, Data: iso.AidCardManager},
+		"case 3 extended":   {Ins: iso.InsPerformSecurityOperation, Data: bytes.Repeat([]byte{0xA5}, 300)},
+		"case 4 standard":   {Ins: iso.InsSelect, P1: 0x04, Data: iso.AidCardManager, Ne: iso.MaxLenResponseDataStandard},
+		"case 4 extended":   {Ins: iso.InsPerformSecurityOperation, Data: []byte{0x01}, Ne: iso.MaxLenResponseDataExtended},
+	} {
+		t.Run(n, func(t *testing.T) {
+			require := require.New(t)
+
+			b, err := c.Bytes()
+			require.NoError(err)
+
+			c2, err := iso.ParseCAPDU(b)
+			require.NoError(err)
+			require.Equal(c.Cla, c2.Cla)
+			require.Equal(c.Ins, c2.Ins)
+			require.Equal(c.P1, c2.P1)
+			require.Equal(c.P2, c2.P2)
+			require.Equal(c.Ne, c2.Ne)
+			require.Equal(c.Data, c2.Data)
+
+			b2, err := c2.Bytes()
+			require.NoError(err)
+			require.Equal(b, b2)
+		})
+	}
+}
+
+func TestParseCAPDUError(t *testing.T) {
+	for n, s := range map[string]string{
+		"short header":          "00a404",
+		"standard lc too short": "00a4040005a000",
+		"standard lc too long":  "00a4040002a00000000000",
+		"extended lc zero":      "00a4040000000000",
+		"extended lc mismatch":  "00a40400000002a0",
+		"extended le truncated": "00a40400000001a000",
+		"body length 2":         "00a404000000",
+	} {
+		t.Run(n, func(t *testing.T) {
+			b, err := hex.DecodeString(s)
+			require.NoError(t, err)
+
+			_, err = iso.ParseCAPDU(b)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRAPDUBytes(t *testing.T) {
+	require := require.New(t)
+
+	for _, s := range []string{"9000", "01026a82"} {
+		b, err := hex.DecodeString(s)
+		require.NoError(err)
+
+		r, err := iso.ParseRAPDU(b)
+		require.NoError(err)
+
+		b2, err := r.Bytes()
+		require.NoError(err)
+		require.Equal(b, b2)
+	}
+
+	_, err := (&iso.RAPDU{Data: make([]byte, iso.MaxLenResponseDataExtended+1)}).Bytes()
+	require.Error(err)
+}
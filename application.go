@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+// Elementary files for application discovery
+// See: ISO 7816-4 Section 8.2.1.1 EF.DIR and EF.ATR/INFO
+const (
+	FileIDDIR uint16 = 0x2F00 // EF.DIR
+	FileIDATR uint16 = 0x2F01 // EF.ATR/INFO
+
+	sfiDIR = 0x1E // Short EF identifier of EF.DIR
+	sfiATR = 0x1D // Short EF identifier of EF.ATR/INFO
+)
+
+// Interindustry data objects for application identification and selection
+// See: ISO 7816-4 Section 8.2.1.1 EF.DIR
+const (
+	TagApplicationTemplate       tlv.Tag = 0x61   // Application template
+	TagApplicationIdentifier     tlv.Tag = 0x4F   // Application identifier (AID)
+	TagApplicationLabel          tlv.Tag = 0x50   // Application label
+	TagApplicationPath           tlv.Tag = 0x51   // Path
+	TagCommandToPerform          tlv.Tag = 0x52   // Command to perform
+	TagDiscretionaryDataTemplate tlv.Tag = 0x73   // Discretionary data objects
+	TagCardServiceData           tlv.Tag = 0x43   // Card service data
+	TagPreIssuingData            tlv.Tag = 0x46   // Pre-issuing data
+	TagCardCapabilities          tlv.Tag = 0x47   // Card capabilities
+	TagExtendedLengthInfo        tlv.Tag = 0x7F66 // Extended length information
+)
+
+// tagInteger is the ASN.1 tag of an INTEGER used in the extended length information.
+const tagInteger tlv.Tag = 0x02
+
+// Application is an entry of EF.DIR describing an application on the card.
+type Application struct {
+	AID           AID
+	Label         string
+	Path          []byte
+	Command       []byte
+	Discretionary tlv.TagValues
+}
+
+// CardInfo contains the data objects stored in EF.ATR/INFO.
+type CardInfo struct {
+	CardService      CardService
+	CardCapabilities CardCapabilities
+	PreIssuing       []byte
+
+	// MaxLenCmdAPDU and MaxLenRespAPDU are the maximum lengths of command and response
+	// APDUs as indicated by the extended length information or zero if absent.
+	MaxLenCmdAPDU  int
+	MaxLenRespAPDU int
+
+	// TagValues contains all data objects of EF.ATR/INFO.
+	TagValues tlv.TagValues
+}
+
+// Applications returns the applications listed in EF.DIR.
+// EF.DIR is accessed by the method advertised in the card service data of the
+// historical bytes. If the card does not indicate a method, the records of
+// EF.DIR are read with a fallback to READ BINARY for transparent EFs.
+// See: ISO 7816-4 Section 8.2.1.1 EF.DIR
+func (c *Card) Applications() ([]Application, error) {
+	return c.ApplicationsContext(context.Background())
+}
+
+// ApplicationsContext is like Applications but uses the provided context.
+func (c *Card) ApplicationsContext(ctx context.Context) ([]Application, error) {
+	var (
+		tvs tlv.TagValues
+		err error
+	)
+
+	switch c.cardService().AccessServices() {
+	case byte(CardServiceAccessByReadBinaryCmd):
+		tvs, err = c.readDIRBinary(ctx)
+
+	case byte(CardServiceAccessByGetDataCmd):
+		tvs, err = c.getDataList(ctx, FileIDDIR, tlv.New(TagTagList, byte(TagApplicationTemplate)))
+
+	default:
+		if tvs, err = c.readDIRRecords(ctx); errors.Is(err, ErrCommandIncompatibleWithFile) || errors.Is(err, ErrRecordNumberNotSupported) {
+			tvs, err = c.readDIRBinary(ctx)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EF.DIR: %w", err)
+	}
+
+	apps := []Application{}
+
+	for _, tv := range tvs.GetAll(TagApplicationTemplate) {
+		app := Application{}
+
+		for _, child := range tv.Children {
+			switch child.Tag {
+			case TagApplicationIdentifier:
+				app.AID = child.Value
+			case TagApplicationLabel:
+				app.Label = string(child.Value)
+			case TagApplicationPath:
+				app.Path = child.Value
+			case TagCommandToPerform:
+				app.Command = child.Value
+			case TagDiscretionaryDataTemplate:
+				app.Discretionary = child.Children
+			}
+		}
+
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// CardInfo reads the data objects of EF.ATR/INFO.
+// See: ISO 7816-4 Section 8.2.1.1 EF.ATR/INFO
+func (c *Card) CardInfo() (*CardInfo, error) {
+	return c.CardInfoContext(context.Background())
+}
+
+// CardInfoContext is like CardInfo but uses the provided context.
+func (c *Card) CardInfoContext(ctx context.Context) (*CardInfo, error) {
+	b, err := c.ReadBinaryContext(ctx, sfiATR, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EF.ATR/INFO: %w", err)
+	}
+
+	return parseCardInfo(b)
+}
+
+// parseCardInfo decodes the contents of EF.ATR/INFO.
+func parseCardInfo(b []byte) (*CardInfo, error) {
+	tvs, err := decodePaddedBER(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode EF.ATR/INFO: %w", err)
+	}
+
+	info := &CardInfo{
+		TagValues: tvs,
+	}
+
+	for _, tv := range tvs {
+		switch tv.Tag {
+		case TagCardServiceData:
+			err = info.CardService.Decode(tv.Value)
+		case TagCardCapabilities:
+			err = info.CardCapabilities.Decode(tv.Value)
+		case TagPreIssuingData:
+			info.PreIssuing = tv.Value
+		case TagExtendedLengthInfo:
+			err = info.decodeExtendedLengthInfo(tv.Children)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data object %X: %w", tv.Tag, err)
+		}
+	}
+
+	return info, nil
+}
+
+// decodeExtendedLengthInfo decodes the two integers of the extended length information.
+// See: ISO 7816-4 Section 8.1.1.2.7 Card capabilities
+func (i *CardInfo) decodeExtendedLengthInfo(tvs tlv.TagValues) error {
+	ints := tvs.GetAll(tagInteger)
+	if len(ints) != 2 {
+		return fmt.Errorf("%w: expected two integers", errInvalidLength)
+	}
+
+	i.MaxLenCmdAPDU = decodeUint(ints[0].Value)
+	i.MaxLenRespAPDU = decodeUint(ints[1].Value)
+
+	return nil
+}
+
+// historicalBytes returns the decoded historical bytes
+// or nil if the card does not provide its ATR.
+func (c *Card) historicalBytes() *HistoricalBytes {
+	atr, err := c.ATR()
+	if err != nil {
+		return nil
+	}
+
+	hb, err := atr.HistoricalBytes()
+	if err != nil {
+		return nil
+	}
+
+	return hb
+}
+
+// cardService returns the card service data of the historical bytes
+// or zero if the card does not provide its ATR.
+func (c *Card) cardService() CardService {
+	if hb := c.historicalBytes(); hb != nil {
+		return hb.CardService
+	}
+
+	return 0
+}
+
+func (c *Card) readDIRRecords(ctx context.Context) (tvs tlv.TagValues, err error) {
+	records, err := c.ReadRecordsContext(ctx, sfiDIR)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		rtvs, err := decodePaddedBER(record)
+		if err != nil {
+			return nil, err
+		}
+
+		tvs = append(tvs, rtvs...)
+	}
+
+	return tvs, nil
+}
+
+func (c *Card) readDIRBinary(ctx context.Context) (tlv.TagValues, error) {
+	b, err := c.ReadBinaryContext(ctx, sfiDIR, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePaddedBER(b)
+}
+
+// decodePaddedBER decodes BER-TLV data objects which
+// might be separated or followed by padding bytes '00' or 'FF'.
+// See: ISO 7816-4 Section 5.2.2 BER-TLV data objects
+func decodePaddedBER(b []byte) (tvs tlv.TagValues, err error) {
+	for {
+		for len(b) > 0 && (b[0] == 0x00 || b[0] == 0xFF) {
+			b = b[1:]
+		}
+
+		if len(b) == 0 {
+			return tvs, nil
+		}
+
+		var tv tlv.TagValue
+		if b, err = tv.UnmarshalBER(b); err != nil {
+			return nil, err
+		}
+
+		tvs = append(tvs, tv)
+	}
+}
@@ -0,0 +1,436 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrInvalidATR      = errors.New("invalid ATR")
+	ErrATRNotAvailable = errors.New("card does not provide an ATR")
+)
+
+const (
+	// MaxLenATR is the maximum length of an answer-to-reset including TS.
+	// See: ISO 7816-3 Section 8.2.1
+	MaxLenATR = 33
+
+	maxLenHistoricalBytes = 15
+)
+
+// ATRCard is implemented by cards which provide the answer-to-reset
+// received from the card.
+type ATRCard interface {
+	ATR() (*ATR, error)
+}
+
+// ATR returns the answer-to-reset of the underlying card.
+// Cards, Transactions and WrappingCards in between are passed through.
+func (c *Card) ATR() (*ATR, error) {
+	for pc := c.PCSCCard; pc != nil; {
+		if ac, ok := pc.(ATRCard); ok {
+			return ac.ATR()
+		}
+
+		w, ok := pc.(WrappingCard)
+		if !ok {
+			break
+		}
+
+		pc = w.Wrapped()
+	}
+
+	return nil, ErrATRNotAvailable
+}
+
+// Convention is the encoding convention indicated by the initial character TS.
+// See: ISO 7816-3 Section 8.1
+type Convention byte
+
+const (
+	ConventionDirect  Convention = 0x3B
+	ConventionInverse Convention = 0x3F
+)
+
+// Protocol is a transmission protocol type T as indicated by the TDi interface bytes.
+// See: ISO 7816-3 Section 8.2.3
+type Protocol byte
+
+const (
+	ProtocolT0  Protocol = 0  // Half-duplex transmission of characters
+	ProtocolT1  Protocol = 1  // Half-duplex transmission of blocks
+	ProtocolT15 Protocol = 15 // No transmission protocol, qualifies global interface bytes
+)
+
+func (p Protocol) String() string {
+	return fmt.Sprintf("T=%d", byte(p))
+}
+
+// Clock rate conversion integers Fi and maximum frequencies f(max) in kHz indexed by FI
+// See: ISO 7816-3 Section 8.3 Table 7
+//
+//nolint:gochecknoglobals
+var (
+	atrFi   = [16]int{372, 372, 558, 744, 1116, 1488, 1860, 0, 0, 512, 768, 1024, 1536, 2048, 0, 0}
+	atrFMax = [16]int{4000, 5000, 6000, 8000, 12000, 16000, 20000, 0, 0, 5000, 7500, 10000, 15000, 20000, 0, 0}
+	atrDi   = [16]int{0, 1, 2, 4, 8, 16, 32, 64, 12, 20, 0, 0, 0, 0, 0, 0}
+)
+
+const (
+	defaultTA1  = 0x11 // Fi=372, Di=1
+	defaultIFSC = 32
+)
+
+// InterfaceBytes is a group of the interface bytes TAi, TBi and TCi.
+// See: ISO 7816-3 Section 8.2.3
+type InterfaceBytes struct {
+	// Protocol is the protocol type T indicated by the preceding TD(i-1) byte.
+	// It is ignored for the first group which only contains global interface bytes.
+	Protocol Protocol
+
+	TA, TB, TC          byte
+	HasTA, HasTB, HasTC bool
+}
+
+func (g InterfaceBytes) indicator() (y byte) {
+	if g.HasTA {
+		y |= 0x1
+	}
+
+	if g.HasTB {
+		y |= 0x2
+	}
+
+	if g.HasTC {
+		y |= 0x4
+	}
+
+	return y
+}
+
+// ATR is the answer-to-reset of a card.
+// See: ISO 7816-3 Section 8 Answer-to-Reset
+type ATR struct {
+	Convention Convention
+
+	// Interface contains the groups of interface bytes.
+	// The i-th group contains TA(i+1), TB(i+1) and TC(i+1).
+	// The TDi bytes are derived from the protocol of the following group.
+	Interface []InterfaceBytes
+
+	// Historical contains the raw historical bytes.
+	// Use HistoricalBytes() to decode them.
+	Historical []byte
+}
+
+// ParseATR decodes an answer-to-reset and verifies its check byte TCK.
+func ParseATR(b []byte) (*ATR, error) {
+	if len(b) < 2 || len(b) > MaxLenATR {
+		return nil, fmt.Errorf("%w: length must be between 2 and %d bytes, got %d", ErrInvalidATR, MaxLenATR, len(b))
+	}
+
+	a := &ATR{
+		Convention: Convention(b[0]),
+	}
+
+	if a.Convention != ConventionDirect && a.Convention != ConventionInverse {
+		return nil, fmt.Errorf("%w: invalid initial character TS %#02x", ErrInvalidATR, b[0])
+	}
+
+	t0 := b[1]
+	k := int(t0 & 0xF)
+	y := t0 >> 4
+	buf := b[2:]
+
+	next := func() (byte, error) {
+		if len(buf) < 1 {
+			return 0, fmt.Errorf("%w: missing interface bytes", ErrInvalidATR)
+		}
+
+		c := buf[0]
+		buf = buf[1:]
+
+		return c, nil
+	}
+
+	hasTCK := false
+
+	for proto := ProtocolT0; ; {
+		var err error
+
+		g := InterfaceBytes{
+			Protocol: proto,
+		}
+
+		if y&0x1 != 0 {
+			if g.TA, err = next(); err != nil {
+				return nil, err
+			}
+			g.HasTA = true
+		}
+
+		if y&0x2 != 0 {
+			if g.TB, err = next(); err != nil {
+				return nil, err
+			}
+			g.HasTB = true
+		}
+
+		if y&0x4 != 0 {
+			if g.TC, err = next(); err != nil {
+				return nil, err
+			}
+			g.HasTC = true
+		}
+
+		a.Interface = append(a.Interface, g)
+
+		if y&0x8 == 0 {
+			break
+		}
+
+		td, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		y = td >> 4
+		proto = Protocol(td & 0xF)
+
+		// The check byte is absent if only T=0 is indicated
+		if proto != ProtocolT0 {
+			hasTCK = true
+		}
+	}
+
+	if len(buf) < k {
+		return nil, fmt.Errorf("%w: expected %d historical bytes, got %d", ErrInvalidATR, k, len(buf))
+	}
+
+	a.Historical = buf[:k]
+	buf = buf[k:]
+
+	if hasTCK {
+		if len(buf) < 1 {
+			return nil, fmt.Errorf("%w: missing check byte TCK", ErrInvalidATR)
+		}
+
+		if checksum(b[1:]) != 0 {
+			return nil, fmt.Errorf("%w: check byte TCK mismatch", ErrInvalidATR)
+		}
+
+		buf = buf[1:]
+	}
+
+	if len(buf) > 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidATR, len(buf))
+	}
+
+	return a, nil
+}
+
+// Bytes encodes the answer-to-reset.
+// The check byte TCK is appended if a protocol other than T=0 is indicated.
+func (a *ATR) Bytes() ([]byte, error) {
+	if len(a.Historical) > maxLenHistoricalBytes {
+		return nil, fmt.Errorf("%w: too many historical bytes: %d", ErrInvalidATR, len(a.Historical))
+	}
+
+	groups := a.Interface
+	if len(groups) == 0 {
+		groups = []InterfaceBytes{{}}
+	}
+
+	hasTD := len(groups) > 1
+	t0 := groups[0].indicator() << 4
+	if hasTD {
+		t0 |= 0x80
+	}
+
+	b := []byte{byte(a.Convention), t0 | byte(len(a.Historical))}
+	hasTCK := false
+
+	for i, g := range groups {
+		if g.HasTA {
+			b = append(b, g.TA)
+		}
+
+		if g.HasTB {
+			b = append(b, g.TB)
+		}
+
+		if g.HasTC {
+			b = append(b, g.TC)
+		}
+
+		if i+1 < len(groups) {
+			n := groups[i+1]
+			if n.Protocol > ProtocolT15 {
+				return nil, fmt.Errorf("%w: invalid protocol %d", ErrInvalidATR, n.Protocol)
+			}
+
+			td := n.indicator()<<4 | byte(n.Protocol)
+			if i+2 < len(groups) {
+				td |= 0x80
+			}
+
+			if n.Protocol != ProtocolT0 {
+				hasTCK = true
+			}
+
+			b = append(b, td)
+		}
+	}
+
+	b = append(b, a.Historical...)
+
+	if hasTCK {
+		b = append(b, checksum(b[1:]))
+	}
+
+	if len(b) > MaxLenATR {
+		return nil, fmt.Errorf("%w: length %d exceeds maximum of %d bytes", ErrInvalidATR, len(b), MaxLenATR)
+	}
+
+	return b, nil
+}
+
+// Protocols returns the transmission protocols offered by the card.
+// T=0 is assumed if TD1 is absent.
+func (a *ATR) Protocols() (protos []Protocol) {
+	for _, g := range a.tdGroups() {
+		if g.Protocol == ProtocolT15 || slices.Contains(protos, g.Protocol) {
+			continue
+		}
+
+		protos = append(protos, g.Protocol)
+	}
+
+	if len(protos) == 0 {
+		protos = append(protos, ProtocolT0)
+	}
+
+	return protos
+}
+
+// Fi returns the clock rate conversion integer indicated by TA1.
+// Zero is returned for reserved values.
+func (a *ATR) Fi() int {
+	return atrFi[a.ta1()>>4]
+}
+
+// FMax returns the maximum clock frequency in kHz indicated by TA1.
+// Zero is returned for reserved values.
+func (a *ATR) FMax() int {
+	return atrFMax[a.ta1()>>4]
+}
+
+// Di returns the baud rate adjustment integer indicated by TA1.
+// Zero is returned for reserved values.
+func (a *ATR) Di() int {
+	return atrDi[a.ta1()&0xF]
+}
+
+// ExtraGuardTime returns the extra guard time integer N indicated by TC1.
+func (a *ATR) ExtraGuardTime() int {
+	if len(a.Interface) > 0 && a.Interface[0].HasTC {
+		return int(a.Interface[0].TC)
+	}
+
+	return 0
+}
+
+// SpecificMode returns the protocol of the specific mode if indicated by TA2.
+func (a *ATR) SpecificMode() (Protocol, bool) {
+	if len(a.Interface) > 1 && a.Interface[1].HasTA {
+		return Protocol(a.Interface[1].TA & 0xF), true
+	}
+
+	return 0, false
+}
+
+// IFSC returns the maximum information field size of the card for T=1.
+// It is indicated by the first TA byte for T=1 in the third or a following group.
+func (a *ATR) IFSC() int {
+	if g, ok := a.specific(ProtocolT1); ok && g.HasTA {
+		return int(g.TA)
+	}
+
+	return defaultIFSC
+}
+
+// ClockStop returns the clock stop indicator X from the first TA byte for T=15.
+func (a *ATR) ClockStop() (byte, bool) {
+	if g, ok := a.specific(ProtocolT15); ok && g.HasTA {
+		return g.TA >> 6, true
+	}
+
+	return 0, false
+}
+
+// Classes returns the class indicator Y from the first TA byte for T=15.
+// Each bit indicates a supported class of operating conditions.
+func (a *ATR) Classes() (byte, bool) {
+	if g, ok := a.specific(ProtocolT15); ok && g.HasTA {
+		return g.TA & 0x3F, true
+	}
+
+	return 0, false
+}
+
+// HistoricalBytes decodes the historical bytes.
+func (a *ATR) HistoricalBytes() (*HistoricalBytes, error) {
+	h := &HistoricalBytes{}
+	if err := h.Decode(a.Historical); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (a *ATR) ta1() byte {
+	if len(a.Interface) > 0 && a.Interface[0].HasTA {
+		return a.Interface[0].TA
+	}
+
+	return defaultTA1
+}
+
+// tdGroups returns the groups which follow a TDi byte.
+func (a *ATR) tdGroups() []InterfaceBytes {
+	if len(a.Interface) < 2 {
+		return nil
+	}
+
+	return a.Interface[1:]
+}
+
+// specific returns the first group of interface bytes specific to the
+// protocol. Specific interface bytes for T=1 and T=15 start at the
+// third group.
+func (a *ATR) specific(proto Protocol) (InterfaceBytes, bool) {
+	if len(a.Interface) < 3 {
+		return InterfaceBytes{}, false
+	}
+
+	for _, g := range a.Interface[2:] {
+		if g.Protocol == proto {
+			return g, true
+		}
+	}
+
+	return InterfaceBytes{}, false
+}
+
+// checksum returns the exclusive-or of all bytes.
+func checksum(b []byte) (c byte) {
+	for _, d := range b {
+		c ^= d
+	}
+
+	return c
+}
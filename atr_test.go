@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func TestParseATR(t *testing.T) {
+	require := require.New(t)
+
+	// YubiKey NEO
+	atr, err := iso.ParseATR(unhex("3bfd1300008131fe158073c021c057597562694b657940"))
+	require.NoError(err)
+
+	require.Equal(iso.ConventionDirect, atr.Convention)
+	require.Len(atr.Interface, 3)
+	require.Equal([]iso.Protocol{iso.ProtocolT1}, atr.Protocols())
+	require.Equal(372, atr.Fi())
+	require.Equal(4, atr.Di())
+	require.Equal(5000, atr.FMax())
+	require.Equal(254, atr.IFSC())
+	require.Equal(0, atr.ExtraGuardTime())
+
+	_, ok := atr.SpecificMode()
+	require.False(ok)
+
+	hb, err := atr.HistoricalBytes()
+	require.NoError(err)
+	require.Equal(byte(0x80), hb.CategoryIndicator)
+	require.Equal([]byte("YubiKey"), hb.CardIssuer)
+	require.NotZero(hb.CardCapabilities & iso.CardCapCommandChaining)
+}
+
+func TestParseATRGlobalT15(t *testing.T) {
+	require := require.New(t)
+
+	atr, err := iso.ParseATR(unhex("3bdd18ff8191fe1fc3006646530803003671df00008068"))
+	require.NoError(err)
+
+	require.Equal([]iso.Protocol{iso.ProtocolT1}, atr.Protocols())
+	require.Equal(255, atr.ExtraGuardTime())
+	require.Equal(254, atr.IFSC())
+
+	clockStop, ok := atr.ClockStop()
+	require.True(ok)
+	require.Equal(byte(0x3), clockStop)
+
+	classes, ok := atr.Classes()
+	require.True(ok)
+	require.Equal(byte(0x3), classes)
+
+	hb, err := atr.HistoricalBytes()
+	require.NoError(err)
+	require.Equal(byte(0x00), hb.CategoryIndicator)
+	require.Equal(iso.Code{0x00, 0x80}, hb.ProcessingStatus)
+	require.Equal(unhex("465308030036"), hb.PreIssuing)
+}
+
+func TestParseATRDefaults(t *testing.T) {
+	require := require.New(t)
+
+	atr, err := iso.ParseATR(unhex("3b00"))
+	require.NoError(err)
+
+	require.Equal([]iso.Protocol{iso.ProtocolT0}, atr.Protocols())
+	require.Equal(372, atr.Fi())
+	require.Equal(1, atr.Di())
+	require.Equal(32, atr.IFSC())
+	require.Empty(atr.Historical)
+}
+
+func TestATRRoundtrip(t *testing.T) {
+	for _, s := range []string{
+		"3b00",
+		"3b8f01805d4e6974726f6b657900000000006a",
+		"3bdd18ff8191fe1fc3006646530803003671df00008068",
+		"3bf81300008131fe15597562696b657934d4",
+		"3bfc1300008131fe15597562696b65794e454f7233e1",
+		"3bfd1300008131fe158073c021c057597562694b657940",
+		"3b9f95803fc7a08031e073fe211b64076806008290002b", // T=0 and T=15
+	} {
+		t.Run(s, func(t *testing.T) {
+			require := require.New(t)
+
+			atr, err := iso.ParseATR(unhex(s))
+			require.NoError(err)
+
+			b, err := atr.Bytes()
+			require.NoError(err)
+			require.Equal(s, hex.EncodeToString(b))
+		})
+	}
+}
+
+func TestBuildATR(t *testing.T) {
+	require := require.New(t)
+
+	hb := &iso.HistoricalBytes{
+		CategoryIndicator: 0x80,
+		CardIssuer:        []byte("go"),
+		CardCapabilities:  iso.CardCapCommandChaining | iso.CardCapExtendedLength,
+	}
+
+	hbBuf, err := hb.Encode()
+	require.NoError(err)
+
+	atr := &iso.ATR{
+		Convention: iso.ConventionDirect,
+		Interface: []iso.InterfaceBytes{
+			{TA: 0x96, HasTA: true},
+			{Protocol: iso.ProtocolT1},
+			{Protocol: iso.ProtocolT1, TA: 0xFE, HasTA: true},
+		},
+		Historical: hbBuf,
+	}
+
+	b, err := atr.Bytes()
+	require.NoError(err)
+
+	atr2, err := iso.ParseATR(b)
+	require.NoError(err)
+	require.Equal(atr, atr2)
+	require.Equal(512, atr2.Fi())
+	require.Equal(32, atr2.Di())
+	require.Equal(254, atr2.IFSC())
+
+	hb2, err := atr2.HistoricalBytes()
+	require.NoError(err)
+	require.Equal(hb, hb2)
+}
+
+func TestParseATRError(t *testing.T) {
+	for n, s := range map[string]string{
+		"short":        "3b",
+		"convention":   "3a00",
+		"interface":    "3bf0",
+		"historical":   "3b0280",
+		"missing tck":  "3b8001",
+		"invalid tck":  "3b8f01805d4e6974726f6b657900000000006b",
+		"trailing":     "3b0000",
+		"trailing tck": "3b8001810000",
+	} {
+		t.Run(n, func(t *testing.T) {
+			_, err := iso.ParseATR(unhex(s))
+			require.ErrorIs(t, err, iso.ErrInvalidATR)
+		})
+	}
+}
+
+func TestHistoricalBytes(t *testing.T) {
+	for n, hb := range map[string]*iso.HistoricalBytes{
+		"category 0x00": {
+			CategoryIndicator: 0x00,
+			LifeCycleStatus:   0x05,
+			ProcessingStatus:  iso.Code{0x90, 0x00},
+			CountryCode:       []byte{0x02, 0x76},
+			CardService:       iso.CardServiceAppSelectionFullDF,
+		},
+		"category 0x10": {
+			CategoryIndicator: 0x10,
+			DIRDataReference:  0x42,
+		},
+		"category 0x80": {
+			CategoryIndicator: 0x80,
+			AID:               []byte{0xA0, 0x00, 0x00, 0x00, 0x03},
+			LifeCycleStatus:   0x07,
+			ProcessingStatus:  iso.Code{0x90, 0x00},
+		},
+	} {
+		t.Run(n, func(t *testing.T) {
+			require := require.New(t)
+
+			b, err := hb.Encode()
+			require.NoError(err)
+
+			hb2 := &iso.HistoricalBytes{}
+			require.NoError(hb2.Decode(b))
+			require.Equal(hb, hb2)
+		})
+	}
+
+	// Absent historical bytes
+	hb := &iso.HistoricalBytes{CategoryIndicator: 0x10}
+	require.NoError(t, hb.Decode(nil))
+	require.Equal(t, &iso.HistoricalBytes{}, hb)
+}
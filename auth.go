@@ -0,0 +1,259 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+var ErrInvalidAuthenticationTemplate = errors.New("invalid dynamic authentication template")
+
+// Data objects of the dynamic authentication template
+// See: ISO 7816-4 Section 7.5.5 GENERAL AUTHENTICATE command
+const (
+	TagDynamicAuthenticationTemplate tlv.Tag = 0x7C // Dynamic authentication template
+	TagWitness                       tlv.Tag = 0x80 // Witness
+	TagChallenge                     tlv.Tag = 0x81 // Challenge
+	TagResponse                      tlv.Tag = 0x82 // Response
+	TagCommittedChallenge            tlv.Tag = 0x83 // Committed challenge
+	TagAuthenticationCode            tlv.Tag = 0x84 // Authentication code
+	TagExponential                   tlv.Tag = 0x85 // Exponential
+	TagIdentificationDataTemplate    tlv.Tag = 0xA0 // Identification data template
+)
+
+// DynamicAuthenticationTemplate is exchanged in the data fields of GENERAL AUTHENTICATE.
+//
+// Nil fields are omitted from the template while empty non-nil fields
+// are encoded as data objects with length zero. Those are used
+// to request the corresponding data object from the card.
+type DynamicAuthenticationTemplate struct {
+	Witness     []byte
+	Challenge   []byte
+	Response    []byte
+	Exponential []byte
+
+	// Other contains all further data objects of the template
+	// like committed challenges or protocol specific ones.
+	Other tlv.TagValues
+}
+
+// ParseDynamicAuthenticationTemplate decodes a dynamic authentication template ('7C').
+func ParseDynamicAuthenticationTemplate(b []byte) (*DynamicAuthenticationTemplate, error) {
+	tvs, err := tlv.DecodeBER(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAuthenticationTemplate, err)
+	}
+
+	_, children, ok := tvs.Get(TagDynamicAuthenticationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing template", ErrInvalidAuthenticationTemplate)
+	}
+
+	t := &DynamicAuthenticationTemplate{}
+
+	for _, tv := range children {
+		switch tv.Tag {
+		case TagWitness:
+			t.Witness = tv.Value
+		case TagChallenge:
+			t.Challenge = tv.Value
+		case TagResponse:
+			t.Response = tv.Value
+		case TagExponential:
+			t.Exponential = tv.Value
+		default:
+			t.Other = append(t.Other, tv)
+		}
+	}
+
+	return t, nil
+}
+
+// Bytes encodes the dynamic authentication template.
+func (t *DynamicAuthenticationTemplate) Bytes() ([]byte, error) {
+	var children tlv.TagValues
+
+	for _, do := range []struct {
+		tag   tlv.Tag
+		value []byte
+	}{
+		{TagWitness, t.Witness},
+		{TagChallenge, t.Challenge},
+		{TagResponse, t.Response},
+		{TagExponential, t.Exponential},
+	} {
+		if do.value != nil {
+			children = append(children, tlv.TagValue{Tag: do.tag, Value: do.value})
+		}
+	}
+
+	children = append(children, t.Other...)
+
+	return tlv.EncodeBER(tlv.New(TagDynamicAuthenticationTemplate, children))
+}
+
+// GetChallenge requests a challenge of n bytes from the card.
+// See: ISO 7816-4 Section 7.5.3 GET CHALLENGE command
+func (c *Card) GetChallenge(n int) ([]byte, error) {
+	return c.GetChallengeContext(context.Background(), n)
+}
+
+// GetChallengeContext is like GetChallenge but uses the provided context.
+func (c *Card) GetChallengeContext(ctx context.Context, n int) ([]byte, error) {
+	return c.SendContext(ctx, &CAPDU{
+		Ins: InsGetChallenge,
+		P1:  0x00,
+		P2:  0x00,
+		Ne:  n,
+	})
+}
+
+// InternalAuthenticate lets the card compute authentication data from the challenge
+// using the algorithm alg and the key referenced by key.
+// See: ISO 7816-4 Section 7.5.2 INTERNAL AUTHENTICATE command
+func (c *Card) InternalAuthenticate(alg, key byte, challenge []byte) ([]byte, error) {
+	return c.InternalAuthenticateContext(context.Background(), alg, key, challenge)
+}
+
+// InternalAuthenticateContext is like InternalAuthenticate but uses the provided context.
+func (c *Card) InternalAuthenticateContext(ctx context.Context, alg, key byte, challenge []byte) ([]byte, error) {
+	return c.SendContext(ctx, &CAPDU{
+		Ins:  InsInternalAuthenticate,
+		P1:   alg,
+		P2:   key,
+		Data: challenge,
+		Ne:   c.maxLenRespData(),
+	})
+}
+
+// ExternalAuthenticate lets the card verify the response to a previously issued challenge
+// using the algorithm alg and the key referenced by key.
+// See: ISO 7816-4 Section 7.5.4 EXTERNAL (/ MUTUAL) AUTHENTICATE command
+func (c *Card) ExternalAuthenticate(alg, key byte, response []byte) error {
+	return c.ExternalAuthenticateContext(context.Background(), alg, key, response)
+}
+
+// ExternalAuthenticateContext is like ExternalAuthenticate but uses the provided context.
+func (c *Card) ExternalAuthenticateContext(ctx context.Context, alg, key byte, response []byte) error {
+	_, err := c.SendContext(ctx, &CAPDU{
+		Ins:  InsExternalOrMutualAuthenticate,
+		P1:   alg,
+		P2:   key,
+		Data: response,
+	})
+
+	return err
+}
+
+// MutualAuthenticate lets the card verify the authentication data of the
+// outside world and returns the authentication data of the card.
+// See: ISO 7816-4 Section 7.5.4 EXTERNAL (/ MUTUAL) AUTHENTICATE command
+func (c *Card) MutualAuthenticate(alg, key byte, data []byte) ([]byte, error) {
+	return c.MutualAuthenticateContext(context.Background(), alg, key, data)
+}
+
+// MutualAuthenticateContext is like MutualAuthenticate but uses the provided context.
+func (c *Card) MutualAuthenticateContext(ctx context.Context, alg, key byte, data []byte) ([]byte, error) {
+	return c.SendContext(ctx, &CAPDU{
+		Ins:  InsExternalOrMutualAuthenticate,
+		P1:   alg,
+		P2:   key,
+		Data: data,
+		Ne:   c.maxLenRespData(),
+	})
+}
+
+// GeneralAuthenticate performs a single step GENERAL AUTHENTICATE exchange.
+// See: ISO 7816-4 Section 7.5.5 GENERAL AUTHENTICATE command
+func (c *Card) GeneralAuthenticate(alg, key byte, t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return c.GeneralAuthenticateContext(context.Background(), alg, key, t)
+}
+
+// GeneralAuthenticateContext is like GeneralAuthenticate but uses the provided context.
+func (c *Card) GeneralAuthenticateContext(ctx context.Context, alg, key byte, t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return c.NewGeneralAuthentication(alg, key).FinalContext(ctx, t)
+}
+
+// GeneralAuthentication is a multi-step GENERAL AUTHENTICATE exchange
+// as used by protocols like PACE or the PIV card authentication.
+// All steps but the last are sent with the command chaining bit set.
+// See: ISO 7816-4 Section 7.5.5 GENERAL AUTHENTICATE command
+type GeneralAuthentication struct {
+	Card *Card
+
+	// Cla is the class byte of the commands.
+	// The chaining bit is set by the exchange.
+	Cla Class
+
+	// Ins is either InsGeneralAuthenticate or InsGeneralAuthenticateOdd.
+	Ins Instruction
+
+	// Algorithm is the algorithm reference encoded in P1.
+	Algorithm byte
+
+	// Key is the key reference encoded in P2.
+	Key byte
+}
+
+// NewGeneralAuthentication starts a new GENERAL AUTHENTICATE exchange
+// using the algorithm alg and the key referenced by key.
+func (c *Card) NewGeneralAuthentication(alg, key byte) *GeneralAuthentication {
+	return &GeneralAuthentication{
+		Card:      c,
+		Cla:       ClassInterindustry,
+		Ins:       InsGeneralAuthenticateOdd,
+		Algorithm: alg,
+		Key:       key,
+	}
+}
+
+// Step sends an intermediate step of the exchange and returns the template of the card.
+func (a *GeneralAuthentication) Step(t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return a.StepContext(context.Background(), t)
+}
+
+// StepContext is like Step but uses the provided context.
+func (a *GeneralAuthentication) StepContext(ctx context.Context, t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return a.send(ctx, t, true)
+}
+
+// Final sends the last step of the exchange and returns the template of the card.
+func (a *GeneralAuthentication) Final(t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return a.FinalContext(context.Background(), t)
+}
+
+// FinalContext is like Final but uses the provided context.
+func (a *GeneralAuthentication) FinalContext(ctx context.Context, t *DynamicAuthenticationTemplate) (*DynamicAuthenticationTemplate, error) {
+	return a.send(ctx, t, false)
+}
+
+func (a *GeneralAuthentication) send(ctx context.Context, t *DynamicAuthenticationTemplate, chained bool) (*DynamicAuthenticationTemplate, error) {
+	data, err := t.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.Card.SendContext(ctx, &CAPDU{
+		Cla:  a.Cla.WithChaining(chained),
+		Ins:  a.Ins,
+		P1:   a.Algorithm,
+		P2:   a.Key,
+		Data: data,
+		Ne:   a.Card.maxLenRespData(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Some steps are acknowledged without response data
+	if len(resp) == 0 {
+		return &DynamicAuthenticationTemplate{}, nil
+	}
+
+	return ParseDynamicAuthenticationTemplate(resp)
+}
@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+func TestDynamicAuthenticationTemplate(t *testing.T) {
+	require := require.New(t)
+
+	dat := &iso.DynamicAuthenticationTemplate{
+		Witness:  []byte{0x01, 0x02},
+		Response: []byte{},
+		Other: tlv.TagValues{
+			tlv.New(iso.TagCommittedChallenge, byte(0x03)),
+		},
+	}
+
+	b, err := dat.Bytes()
+	require.NoError(err)
+	require.Equal(unhex("7c09800201028200830103"), b)
+
+	dat2, err := iso.ParseDynamicAuthenticationTemplate(b)
+	require.NoError(err)
+	require.Equal([]byte{0x01, 0x02}, dat2.Witness)
+	require.NotNil(dat2.Response)
+	require.Empty(dat2.Response)
+	require.Nil(dat2.Challenge)
+
+	value, _, ok := dat2.Other.Get(iso.TagCommittedChallenge)
+	require.True(ok)
+	require.Equal([]byte{0x03}, value)
+
+	_, err = iso.ParseDynamicAuthenticationTemplate(unhex("8000"))
+	require.ErrorIs(err, iso.ErrInvalidAuthenticationTemplate)
+}
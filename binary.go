@@ -0,0 +1,334 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+// Data objects of commands with odd instruction codes
+// See: ISO 7816-4 Section 7.2.2 Data objects for transparent EFs
+const (
+	TagDiscretionaryData tlv.Tag = 0x53 // Discretionary data
+	TagOffset            tlv.Tag = 0x54 // Offset data object
+)
+
+const (
+	maxOffsetSFI  = 0xFF   // Maximum offset if the EF is referenced by a short EF identifier in P1
+	maxOffsetEven = 0x7FFF // Maximum offset encoded in P1-P2 of even instructions
+
+	// Maximum length of the BER-TLV tag and length fields of the discretionary data object
+	lenDiscretionaryDataHeader = 4
+)
+
+// ReadBinary reads length bytes starting at offset from a transparent EF.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// If length is zero or negative, the file is read until its end.
+// Reads are split into several commands of at most MaxLenRespData bytes.
+// Offsets beyond 32767 are encoded in an offset data object using the odd instruction.
+// Reaching the end of the file is not considered an error.
+// See: ISO 7816-4 Section 7.2.3 READ BINARY command
+func (c *Card) ReadBinary(sfi byte, offset, length int) ([]byte, error) {
+	return c.ReadBinaryContext(context.Background(), sfi, offset, length)
+}
+
+// ReadBinaryContext is like ReadBinary but uses the provided context.
+func (c *Card) ReadBinaryContext(ctx context.Context, sfi byte, offset, length int) (data []byte, err error) {
+	untilEnd := length <= 0
+
+	for eof := false; !eof && (untilEnd || len(data) < length); {
+		cmd, odd := binaryCommand(InsReadBinary, sfi, offset)
+
+		ne := c.maxLenRespData()
+		if odd {
+			// The response data is wrapped into a discretionary data object
+			ne -= lenDiscretionaryDataHeader
+		}
+
+		if !untilEnd {
+			ne = min(ne, length-len(data))
+		}
+
+		cmd.Ne = ne
+
+		if odd {
+			cmd.Ne += discretionaryDataHeaderLen(ne)
+
+			if cmd.Data, err = encodeOffset(offset); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.SendContext(ctx, cmd)
+		if err != nil {
+			var se *StatusError
+
+			switch {
+			case errors.As(err, &se) && se.Code == ErrEOF:
+				// End of file reached before reading Ne bytes
+				resp, eof = se.Data, true
+
+			case errors.Is(err, ErrWrongParams) && len(data) > 0:
+				// Offset outside of the EF as the last chunk ended exactly at the end of the file
+				return data, nil
+
+			default:
+				return nil, err
+			}
+		}
+
+		if odd {
+			if resp, err = decodeDiscretionaryData(resp); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(resp) == 0 {
+			break
+		}
+
+		// A short read indicates the end of the file
+		if len(resp) < ne {
+			eof = true
+		}
+
+		data = append(data, resp...)
+		offset += len(resp)
+
+		// The EF referenced by the short EF identifier is now the current EF
+		sfi = 0
+	}
+
+	return data, nil
+}
+
+// UpdateBinary updates the content of a transparent EF starting at offset with data.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// Data is split into several commands of at most MaxLenCmdData bytes.
+// See: ISO 7816-4 Section 7.2.5 UPDATE BINARY command
+func (c *Card) UpdateBinary(sfi byte, offset int, data []byte) error {
+	return c.UpdateBinaryContext(context.Background(), sfi, offset, data)
+}
+
+// UpdateBinaryContext is like UpdateBinary but uses the provided context.
+func (c *Card) UpdateBinaryContext(ctx context.Context, sfi byte, offset int, data []byte) error {
+	return c.writeBinary(ctx, InsUpdateBinary, sfi, offset, data)
+}
+
+// WriteBinary writes data to a transparent EF starting at offset.
+// Depending on the card, the data is written once or combined with the
+// existing content by a logical OR or AND operation.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// Data is split into several commands of at most MaxLenCmdData bytes.
+// See: ISO 7816-4 Section 7.2.4 WRITE BINARY command
+func (c *Card) WriteBinary(sfi byte, offset int, data []byte) error {
+	return c.WriteBinaryContext(context.Background(), sfi, offset, data)
+}
+
+// WriteBinaryContext is like WriteBinary but uses the provided context.
+func (c *Card) WriteBinaryContext(ctx context.Context, sfi byte, offset int, data []byte) error {
+	return c.writeBinary(ctx, InsWriteBinary, sfi, offset, data)
+}
+
+// EraseBinary erases the content of a transparent EF starting at offset up to,
+// but not including end. If end is zero or negative, the EF is erased until its end.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// See: ISO 7816-4 Section 7.2.7 ERASE BINARY command
+func (c *Card) EraseBinary(sfi byte, offset, end int) error {
+	return c.EraseBinaryContext(context.Background(), sfi, offset, end)
+}
+
+// EraseBinaryContext is like EraseBinary but uses the provided context.
+func (c *Card) EraseBinaryContext(ctx context.Context, sfi byte, offset, end int) (err error) {
+	cmd, odd := binaryCommand(InsEraseBinary, sfi, offset)
+
+	switch {
+	case odd || end > maxOffsetEven:
+		cmd = oddBinaryCommand(InsEraseBinary, sfi)
+
+		if cmd.Data, err = encodeOffset(offset); err != nil {
+			return err
+		}
+
+		if end > 0 {
+			endDO, err := encodeOffset(end)
+			if err != nil {
+				return err
+			}
+
+			cmd.Data = append(cmd.Data, endDO...)
+		}
+
+	case end > 0:
+		cmd.Data = []byte{byte(end >> 8), byte(end)}
+	}
+
+	_, err = c.SendContext(ctx, cmd)
+
+	return err
+}
+
+// SearchBinary searches a transparent EF for the first occurrence of pattern starting at offset.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// It returns the offset of the first occurrence or -1 if the pattern has not been found.
+// See: ISO 7816-4 Section 7.2.6 SEARCH BINARY command
+func (c *Card) SearchBinary(sfi byte, offset int, pattern []byte) (int, error) {
+	return c.SearchBinaryContext(context.Background(), sfi, offset, pattern)
+}
+
+// SearchBinaryContext is like SearchBinary but uses the provided context.
+func (c *Card) SearchBinaryContext(ctx context.Context, sfi byte, offset int, pattern []byte) (int, error) {
+	cmd, odd := binaryCommand(InsSearchBinary, sfi, offset)
+	cmd.Ne = MaxLenRespDataStandard
+
+	if odd {
+		data, err := encodeOffset(offset)
+		if err != nil {
+			return -1, err
+		}
+
+		dd, err := tlv.EncodeBER(tlv.New(TagDiscretionaryData, pattern))
+		if err != nil {
+			return -1, err
+		}
+
+		cmd.Data = append(data, dd...)
+	} else {
+		cmd.Data = pattern
+	}
+
+	resp, err := c.SendContext(ctx, cmd)
+	if err != nil {
+		if errors.Is(err, ErrEOF) {
+			return -1, nil
+		}
+
+		return -1, err
+	}
+
+	if len(resp) == 0 {
+		return -1, nil
+	}
+
+	// The offset is either returned in an offset data object or as plain integer
+	if resp[0] == byte(TagOffset) {
+		tvs, err := tlv.DecodeBER(resp)
+		if err != nil {
+			return -1, err
+		}
+
+		if value, _, ok := tvs.Get(TagOffset); ok {
+			resp = value
+		}
+	}
+
+	return decodeUint(resp), nil
+}
+
+func (c *Card) writeBinary(ctx context.Context, ins Instruction, sfi byte, offset int, data []byte) error {
+	for len(data) > 0 {
+		n := min(len(data), c.maxLenCmdData())
+
+		cmd, odd := binaryCommand(ins, sfi, offset)
+		if odd {
+			offsetDO, err := encodeOffset(offset)
+			if err != nil {
+				return err
+			}
+
+			n = min(len(data), c.maxLenCmdData()-len(offsetDO)-lenDiscretionaryDataHeader)
+
+			dataDO, err := tlv.EncodeBER(tlv.New(TagDiscretionaryData, data[:n]))
+			if err != nil {
+				return err
+			}
+
+			cmd.Data = append(offsetDO, dataDO...)
+		} else {
+			cmd.Data = data[:n]
+		}
+
+		if _, err := c.SendContext(ctx, cmd); err != nil {
+			return fmt.Errorf("failed to write %d bytes at offset %d: %w", n, offset, err)
+		}
+
+		data = data[n:]
+		offset += n
+
+		// The EF referenced by the short EF identifier is now the current EF
+		sfi = 0
+	}
+
+	return nil
+}
+
+// binaryCommand returns a command for accessing a transparent EF.
+// The odd instruction code is used if the offset can not be encoded in P1-P2.
+// In this case, the offset must be provided by an offset data object
+// and P1-P2 reference the EF by its short EF identifier or the current EF.
+// See: ISO 7816-4 Section 7.2.2 Data objects for transparent EFs
+func binaryCommand(ins Instruction, sfi byte, offset int) (*CAPDU, bool) {
+	switch {
+	case sfi != 0 && offset <= maxOffsetSFI:
+		return &CAPDU{Ins: ins, P1: 0x80 | (sfi & 0x1F), P2: byte(offset)}, false
+
+	case sfi == 0 && offset <= maxOffsetEven:
+		return &CAPDU{Ins: ins, P1: byte(offset >> 8), P2: byte(offset)}, false
+
+	default:
+		return oddBinaryCommand(ins, sfi), true
+	}
+}
+
+// oddBinaryCommand returns a command with the odd instruction code
+// whose P1-P2 reference the EF by its short EF identifier or the current EF.
+func oddBinaryCommand(ins Instruction, sfi byte) *CAPDU {
+	return &CAPDU{Ins: ins | 0x01, P1: 0x00, P2: sfi & 0x1F}
+}
+
+// encodeOffset encodes an offset data object.
+func encodeOffset(offset int) ([]byte, error) {
+	var v []byte
+	for o := offset; o > 0 || len(v) == 0; o >>= 8 {
+		v = append([]byte{byte(o)}, v...)
+	}
+
+	return tlv.EncodeBER(tlv.New(TagOffset, v))
+}
+
+// discretionaryDataHeaderLen returns the length of the BER-TLV tag and
+// length fields of a discretionary data object containing n bytes.
+func discretionaryDataHeaderLen(n int) int {
+	switch {
+	case n < 0x80:
+		return 2
+	case n <= 0xFF:
+		return 3
+	default:
+		return lenDiscretionaryDataHeader
+	}
+}
+
+// decodeDiscretionaryData decodes the response data of odd instructions.
+func decodeDiscretionaryData(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	tvs, err := tlv.DecodeBER(b)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _, ok := tvs.Get(TagDiscretionaryData)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing discretionary data object", errInvalidLength)
+	}
+
+	return value, nil
+}
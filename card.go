@@ -4,11 +4,13 @@
 package iso7816
 
 import (
+	"context"
 	"fmt"
 )
 
 type ReconnectableCard interface {
 	Reconnect(reset bool) error
+	ReconnectContext(ctx context.Context, reset bool) error
 }
 
 type ReaderCard interface {
@@ -27,47 +29,221 @@ type PCSCCard interface {
 	Base() PCSCCard
 }
 
+// WrappingCard is implemented by PCSCCards which wrap another card
+// like the secure messaging Card of package sm.
+type WrappingCard interface {
+	Wrapped() PCSCCard
+}
+
+// ContextCard is implemented by cards which support the cancellation
+// of pending operations via a context.
+type ContextCard interface {
+	TransmitContext(ctx context.Context, cmd []byte) ([]byte, error)
+	BeginTransactionContext(ctx context.Context) error
+}
+
 type Card struct {
 	PCSCCard
 
 	InsGetRemaining Instruction
+
+	// MaxLenCmdData is the maximum length of the command data field (Nc)
+	// which is sent in a single CAPDU. Commands with larger data fields
+	// are split into a command chain.
+	MaxLenCmdData int
+
+	// MaxLenRespData is the maximum length of the response data field (Ne)
+	// which is requested by a single command. Helpers like ReadBinary()
+	// split larger reads into several commands.
+	MaxLenRespData int
+
+	// UseEnvelope enables the transport of commands requiring extended
+	// length fields via ENVELOPE commands.
+	// This should be used for cards communicating via the T=0 protocol.
+	UseEnvelope bool
+
+	channel int
+	limits  *TransferLimits
+
+	lock *CardLock
+	tx   *txState // The transaction to which this card belongs
 }
 
+// NewCard wraps a PCSCCard into a Card.
+// Cards wrapping the same LockingCard share its lock which serializes their access.
+// Wrapping a Card or Transaction, either directly or via WrappingCards,
+// inherits its logical channel, transfer limits and lock. A Card wrapping a
+// Transaction belongs to the transaction until it is closed.
 func NewCard(c PCSCCard) *Card {
-	return &Card{
+	lock, tx := lockOf(c)
+
+	card := &Card{
 		PCSCCard: c,
 
 		// Some applets like Yubico's OATH applet use a different
 		// command for fetching remaining data
 		InsGetRemaining: InsGetResponse,
+
+		MaxLenCmdData:  MaxLenCmdDataStandard,
+		MaxLenRespData: MaxLenRespDataStandard,
+
+		lock: lock,
+		tx:   tx,
 	}
+
+	if w := wrappedCard(c); w != nil {
+		card.MaxLenCmdData = w.MaxLenCmdData
+		card.MaxLenRespData = w.MaxLenRespData
+		card.channel = w.channel
+		card.limits = w.limits
+	}
+
+	return card
 }
 
+// Select selects an application by its AID.
+// The expected response length is limited by MaxLenRespData.
 func (c *Card) Select(aid []byte) (respBuf []byte, err error) {
-	return c.Send(&CAPDU{
+	return c.SelectContext(context.Background(), aid)
+}
+
+// SelectContext is like Select but uses the provided context.
+func (c *Card) SelectContext(ctx context.Context, aid []byte) (respBuf []byte, err error) {
+	return c.SendContext(ctx, &CAPDU{
 		Ins:  InsSelect,
 		P1:   0x04,
 		P2:   0x00,
 		Data: aid,
-		Ne:   MaxLenRespDataStandard,
+		Ne:   c.maxLenRespData(),
 	})
 }
 
+func (c *Card) maxLenCmdData() int {
+	if c.MaxLenCmdData <= 0 {
+		return MaxLenCmdDataStandard
+	}
+
+	return c.MaxLenCmdData
+}
+
+func (c *Card) maxLenRespData() int {
+	if c.MaxLenRespData <= 0 {
+		return MaxLenRespDataStandard
+	}
+
+	return c.MaxLenRespData
+}
+
 // If checks that the card meets the provided filter condition.
 func (c *Card) If(flt func(card PCSCCard) (bool, error)) (bool, error) {
 	return flt(c)
 }
 
-// Send sends a command APDU to the card
-// nolint: unparam
+// Send sends a command APDU to the card.
+// Commands with a data field larger than MaxLenCmdData are
+// transparently split into a command chain unless the negotiated limits
+// indicate that the card does not support command chaining. Extended length fields
+// are used for commands which fit into the negotiated limits.
+// After Negotiate() has been called, the expected response length is limited
+// to MaxLenRespData and remaining data is fetched with GET RESPONSE.
+// Cards which complete such a limited command without indicating remaining
+// data return at most MaxLenRespData bytes. Hence, callers expecting larger
+// responses must check the length of the returned data.
+// The number of the logical channel is encoded into the class byte
+// if the card is bound to a channel other than the basic channel.
+// Send is safe for concurrent use. The command including its command chain
+// and the retrieval of remaining response data is not interleaved with
+// commands of other goroutines. Use a Transaction to group several commands.
 func (c *Card) Send(cmd *CAPDU) (respBuf []byte, err error) {
-	cmdBuf, err := cmd.Bytes()
+	return c.SendContext(context.Background(), cmd)
+}
+
+// SendContext is like Send but uses the provided context.
+// Canceling the context aborts any pending chained commands or
+// retrieval of remaining response data.
+func (c *Card) SendContext(ctx context.Context, cmd *CAPDU) (respBuf []byte, err error) {
+	ctx, release, err := c.acquire(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize CAPDU: %w", err)
+		return nil, err
+	}
+
+	defer release()
+
+	if c.channel != 0 {
+		cla, err := cmd.Cla.WithChannel(c.channel)
+		if err != nil {
+			return nil, err
+		}
+
+		chCmd := *cmd
+		chCmd.Cla = cla
+		cmd = &chCmd
+	}
+
+	if maxLen := c.maxLenRespData(); c.limits != nil && cmd.Ne > maxLen {
+		chunkCmd := *cmd
+		chunkCmd.Ne = maxLen
+		cmd = &chunkCmd
+	}
+
+	if maxLen := c.maxLenCmdData(); len(cmd.Data) > maxLen {
+		if c.limits != nil && !c.limits.CommandChaining {
+			return nil, fmt.Errorf("%w: command data of %d bytes exceeds maximum of %d bytes", ErrCommandChainingNotSupported, len(cmd.Data), maxLen)
+		}
+
+		return c.sendChained(ctx, cmd, maxLen)
+	}
+
+	return c.send(ctx, cmd)
+}
+
+// sendChained splits the command data into chunks of maxLen bytes and sends
+// them as a command chain by setting the chaining bit in the class byte
+// of all but the last command.
+// See: ISO 7816-4 Section 5.1.1.1 Command chaining
+func (c *Card) sendChained(ctx context.Context, cmd *CAPDU, maxLen int) (respBuf []byte, err error) {
+	cnt := (len(cmd.Data) + maxLen - 1) / maxLen
+
+	for i := 0; i < cnt; i++ {
+		data := cmd.Data[i*maxLen:]
+		if len(data) > maxLen {
+			data = data[:maxLen]
+		}
+
+		link := &CAPDU{
+			Cla:  cmd.Cla,
+			Ins:  cmd.Ins,
+			P1:   cmd.P1,
+			P2:   cmd.P2,
+			Data: data,
+		}
+
+		if last := i == cnt-1; last {
+			link.Ne = cmd.Ne
+		} else {
+			link.Cla = link.Cla.WithChaining(true)
+		}
+
+		if respBuf, err = c.send(ctx, link); err != nil {
+			return nil, fmt.Errorf("failed to send command %d of chain with %d commands: %w", i+1, cnt, err)
+		}
+	}
+
+	return respBuf, nil
+}
+
+func (c *Card) send(ctx context.Context, cmd *CAPDU) (respBuf []byte, err error) {
+	if c.UseEnvelope && cmd.isExtended() {
+		return c.sendEnveloped(ctx, cmd)
 	}
 
 	for {
-		r, err := c.Transmit(cmdBuf)
+		cmdBuf, err := cmd.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize CAPDU: %w", err)
+		}
+
+		r, err := c.transmit(ctx, cmdBuf)
 		if err != nil {
 			return nil, fmt.Errorf("failed to transmit CAPDU: %w", err)
 		}
@@ -82,29 +258,87 @@ func (c *Card) Send(cmd *CAPDU) (respBuf []byte, err error) {
 
 		switch {
 		case respCode.HasMore():
-			cmdBuf = []byte{0x00, byte(c.InsGetRemaining), 0x00, 0x00}
+			// Fetch remaining data with GET RESPONSE while
+			// retaining logical channel and secure messaging indication.
+			// See: ISO 7816-4 Section 7.6.1 GET RESPONSE command
+			cmd = &CAPDU{
+				Cla: cmd.Cla.WithChaining(false),
+				Ins: c.InsGetRemaining,
+				P1:  0x00,
+				P2:  0x00,
+				Ne:  respCode.Available(),
+			}
+
+		case respCode.HasWrongLe():
+			// Re-issue the same command with the exact length
+			// See: ISO 7816-3 Section 10.3.3 Case 2
+			if cmd.Ne == respCode.Available() {
+				return nil, newStatusError(cmd, respCode, respBuf)
+			}
+
+			reissued := *cmd
+			reissued.Ne = respCode.Available()
+			cmd = &reissued
 
 		case respCode.IsSuccess():
 			return respBuf, nil
 
 		default:
-			return nil, respCode
+			return nil, newStatusError(cmd, respCode, respBuf)
 		}
 	}
 }
 
-type Transaction struct {
-	*Card
+// sendEnveloped transports a command which requires extended length fields
+// in the data field of one or more ENVELOPE commands.
+// This is required for cards using the T=0 protocol which can not
+// transport extended length APDUs directly.
+// See: ISO 7816-4 Section 7.6.2 ENVELOPE command
+func (c *Card) sendEnveloped(ctx context.Context, cmd *CAPDU) (respBuf []byte, err error) {
+	cmdBuf, err := cmd.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize CAPDU: %w", err)
+	}
+
+	return c.sendChained(ctx, &CAPDU{
+		Cla:  cmd.Cla.WithChaining(false),
+		Ins:  InsEnvelope,
+		P1:   0x00,
+		P2:   0x00,
+		Data: cmdBuf,
+	}, MaxLenCmdDataStandard)
 }
 
-func (c *Card) NewTransaction() (*Transaction, error) {
-	if err := c.BeginTransaction(); err != nil {
+// Transmit transmits a raw command APDU to the card.
+func (c *Card) Transmit(cmd []byte) ([]byte, error) {
+	return c.TransmitContext(context.Background(), cmd)
+}
+
+// TransmitContext transmits a raw command APDU to the card.
+// The context is passed to the underlying card if it implements
+// the ContextCard interface.
+// Like Send, it waits for pending commands and transactions
+// of other goroutines to complete.
+func (c *Card) TransmitContext(ctx context.Context, cmd []byte) ([]byte, error) {
+	ctx, release, err := c.acquire(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	return &Transaction{c}, nil
+	defer release()
+
+	return c.transmit(ctx, cmd)
 }
 
-func (tx *Transaction) Close() error {
-	return tx.EndTransaction()
+func (c *Card) transmit(ctx context.Context, cmd []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	base := c.base()
+	if cc, ok := base.(ContextCard); ok {
+		return cc.TransmitContext(ctx, cmd)
+	}
+
+	return base.Transmit(cmd)
 }
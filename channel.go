@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxLogicalChannels is the maximum number of logical channels
+// which can be encoded in the class byte.
+// See: ISO 7816-4 Section 5.1.1 Class byte
+const MaxLogicalChannels = 20
+
+var ErrInvalidChannel = errors.New("invalid logical channel number")
+
+// Channel returns the number of the logical channel to which the card is bound.
+func (c *Card) Channel() int {
+	return c.channel
+}
+
+// OpenChannel opens a new logical channel whose number is assigned by the card.
+// The returned card is bound to the new logical channel and shares the
+// underlying PCSCCard with c.
+// See: ISO 7816-4 Section 7.1.2 MANAGE CHANNEL command
+func (c *Card) OpenChannel() (*Card, error) {
+	resp, err := c.Send(&CAPDU{
+		Ins: InsManageChannel,
+		P1:  0x00,
+		P2:  0x00,
+		Ne:  1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open logical channel: %w", err)
+	}
+
+	if len(resp) != 1 {
+		return nil, fmt.Errorf("%w: expected channel number, got %d bytes", errInvalidLength, len(resp))
+	}
+
+	return c.withChannel(int(resp[0]))
+}
+
+// OpenChannelNumber opens the logical channel with the given number.
+// This requires the card to support the channel number assignment by the interface device.
+// See: ISO 7816-4 Section 7.1.2 MANAGE CHANNEL command
+func (c *Card) OpenChannelNumber(ch int) (*Card, error) {
+	if ch <= 0 || ch >= MaxLogicalChannels {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidChannel, ch)
+	}
+
+	if _, err := c.Send(&CAPDU{
+		Ins: InsManageChannel,
+		P1:  0x00,
+		P2:  byte(ch),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to open logical channel: %w", err)
+	}
+
+	return c.withChannel(ch)
+}
+
+// Close closes the logical channel to which the card is bound.
+// For the basic channel, the underlying PCSCCard is closed.
+func (c *Card) Close() error {
+	if c.channel == 0 {
+		return c.PCSCCard.Close()
+	}
+
+	if _, err := c.Send(&CAPDU{
+		Ins: InsManageChannel,
+		P1:  0x80,
+		P2:  byte(c.channel),
+	}); err != nil {
+		return fmt.Errorf("failed to close logical channel: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Card) withChannel(ch int) (*Card, error) {
+	if ch <= 0 || ch >= MaxLogicalChannels {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidChannel, ch)
+	}
+
+	cc := *c
+	cc.channel = ch
+
+	return &cc, nil
+}
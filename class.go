@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidClass = errors.New("invalid class")
+
+// SecureMessaging is the secure messaging indication of the class byte.
+type SecureMessaging byte
+
+const (
+	SecureMessagingNone          SecureMessaging = 0b00 // No SM or no SM indication
+	SecureMessagingProprietary   SecureMessaging = 0b01 // Proprietary SM format
+	SecureMessagingNoHeader      SecureMessaging = 0b10 // SM according to Section 10, command header not processed
+	SecureMessagingAuthenticated SecureMessaging = 0b11 // SM according to Section 10, command header authenticated
+)
+
+// Class is the class byte (CLA) of a command APDU.
+//
+// Proprietary classes (b8 set) are interpreted in the same way as
+// interindustry classes as used by GlobalPlatform (e.g. 0x80, 0x84 or 0xC0).
+//
+// See: ISO 7816-4 Section 5.4.1 Class byte
+type Class byte
+
+const (
+	ClassInterindustry Class = 0x00 // First interindustry class, basic channel without SM
+	ClassProprietary   Class = 0x80 // Proprietary class, basic channel without SM
+	ClassInvalid       Class = 0xFF // Invalid class as used for PPS
+
+	classProprietary Class = 0x80 // b8 indicates a proprietary class
+	classFurther     Class = 0x40 // b7 indicates the further interindustry encoding
+	classChaining    Class = 0x10 // b5 indicates the command chaining control
+	classFurtherSM   Class = 0x20 // b6 indicates SM in the further interindustry encoding
+)
+
+// NewClass creates a new interindustry class for the given logical channel,
+// secure messaging indication and command chaining control.
+func NewClass(channel int, sm SecureMessaging, chaining bool) (Class, error) {
+	return ClassInterindustry.WithChaining(chaining).with(channel, sm)
+}
+
+// NewProprietaryClass creates a new proprietary class for the given logical channel,
+// secure messaging indication and command chaining control.
+func NewProprietaryClass(channel int, sm SecureMessaging, chaining bool) (Class, error) {
+	return ClassProprietary.WithChaining(chaining).with(channel, sm)
+}
+
+// IsValid returns false for the invalid class 0xFF and classes reserved for future use.
+func (c Class) IsValid() bool {
+	return c != ClassInvalid && c&0xE0 != 0x20
+}
+
+// IsInterindustry returns true if the class is a first or further interindustry class.
+func (c Class) IsInterindustry() bool {
+	return c.IsValid() && c&classProprietary == 0
+}
+
+// IsProprietary returns true if the class is a proprietary class.
+func (c Class) IsProprietary() bool {
+	return c.IsValid() && c&classProprietary != 0
+}
+
+func (c Class) isFurther() bool {
+	return c&classFurther != 0
+}
+
+// IsChained returns true if the command is not the last command of a chain.
+// See: ISO 7816-4 Section 5.1.1.1 Command chaining
+func (c Class) IsChained() bool {
+	return c&classChaining != 0
+}
+
+// SecureMessaging returns the secure messaging indication.
+func (c Class) SecureMessaging() SecureMessaging {
+	if c.isFurther() {
+		if c&classFurtherSM != 0 {
+			return SecureMessagingNoHeader
+		}
+
+		return SecureMessagingNone
+	}
+
+	return SecureMessaging(c>>2) & 0b11
+}
+
+// Channel returns the logical channel number.
+func (c Class) Channel() int {
+	if c.isFurther() {
+		return int(c&0xF) + 4
+	}
+
+	return int(c & 0x3)
+}
+
+// WithChaining returns a copy of the class with the command chaining control set.
+func (c Class) WithChaining(chaining bool) Class {
+	if chaining {
+		return c | classChaining
+	}
+
+	return c &^ classChaining
+}
+
+// WithSecureMessaging returns a copy of the class with the secure messaging indication set.
+func (c Class) WithSecureMessaging(sm SecureMessaging) (Class, error) {
+	return c.with(c.Channel(), sm)
+}
+
+// WithChannel returns a copy of the class with the logical channel number set.
+// The first interindustry encoding is used for channels 0-3, the further
+// interindustry encoding for channels 4-19.
+// See: ISO 7816-4 Section 5.1.1 Class byte
+func (c Class) WithChannel(channel int) (Class, error) {
+	return c.with(channel, c.SecureMessaging())
+}
+
+func (c Class) with(channel int, sm SecureMessaging) (Class, error) {
+	if !c.IsValid() {
+		return 0, fmt.Errorf("%w: %#x", ErrInvalidClass, byte(c))
+	}
+
+	if channel < 0 || channel >= MaxLogicalChannels {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidChannel, channel)
+	}
+
+	d := c & (classProprietary | classChaining)
+
+	if channel < 4 {
+		return d | Class(sm&0b11)<<2 | Class(channel), nil
+	}
+
+	switch sm {
+	case SecureMessagingNone:
+	case SecureMessagingNoHeader:
+		d |= classFurtherSM
+	default:
+		return 0, fmt.Errorf("%w: secure messaging indication %d can not be encoded for channel %d", ErrInvalidClass, sm, channel)
+	}
+
+	return d | classFurther | Class(channel-4), nil
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		cla         iso.Class
+		proprietary bool
+		channel     int
+		sm          iso.SecureMessaging
+		chaining    bool
+	}{
+		{0x00, false, 0, iso.SecureMessagingNone, false},
+		{0x03, false, 3, iso.SecureMessagingNone, false},
+		{0x0C, false, 0, iso.SecureMessagingAuthenticated, false},
+		{0x1A, false, 2, iso.SecureMessagingNoHeader, true},
+		{0x40, false, 4, iso.SecureMessagingNone, false},
+		{0x7F, false, 19, iso.SecureMessagingNoHeader, true},
+		{0x80, true, 0, iso.SecureMessagingNone, false},
+		{0x84, true, 0, iso.SecureMessagingProprietary, false},
+		{0xC1, true, 5, iso.SecureMessagingNone, false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%02x", byte(test.cla)), func(t *testing.T) {
+			require := require.New(t)
+
+			require.True(test.cla.IsValid())
+			require.Equal(test.proprietary, test.cla.IsProprietary())
+			require.Equal(!test.proprietary, test.cla.IsInterindustry())
+			require.Equal(test.channel, test.cla.Channel())
+			require.Equal(test.sm, test.cla.SecureMessaging())
+			require.Equal(test.chaining, test.cla.IsChained())
+
+			newClass := iso.NewClass
+			if test.proprietary {
+				newClass = iso.NewProprietaryClass
+			}
+
+			cla, err := newClass(test.channel, test.sm, test.chaining)
+			require.NoError(err)
+			require.Equal(test.cla, cla)
+		})
+	}
+}
+
+func TestClassInvalid(t *testing.T) {
+	require := require.New(t)
+
+	require.False(iso.ClassInvalid.IsValid())
+	require.False(iso.Class(0x20).IsValid())
+
+	_, err := iso.NewClass(iso.MaxLogicalChannels, iso.SecureMessagingNone, false)
+	require.ErrorIs(err, iso.ErrInvalidChannel)
+
+	// Authenticated headers can not be indicated by further interindustry classes
+	_, err = iso.NewClass(4, iso.SecureMessagingAuthenticated, false)
+	require.ErrorIs(err, iso.ErrInvalidClass)
+}
+
+func TestClassWithChannel(t *testing.T) {
+	require := require.New(t)
+
+	cla, err := iso.Class(0x18).WithChannel(7)
+	require.NoError(err)
+	require.Equal(iso.Class(0x73), cla)
+
+	cla, err = cla.WithChannel(1)
+	require.NoError(err)
+	require.Equal(iso.Class(0x19), cla)
+}
@@ -159,14 +159,67 @@ func (c Code) Error() string {
 	case ErrNameAlreadyExists:
 		return "DF name already exists"
 	}
+
+	switch {
+	case c.HasMore():
+		return fmt.Sprintf("%d more data bytes available", c.Available())
+	case c.HasWrongLe():
+		return fmt.Sprintf("wrong Le field; %d data bytes available", c.Available())
+	}
+
+	if retries, ok := c.RetriesLeft(); ok {
+		return fmt.Sprintf("verification failed; %d retries left", retries)
+	}
+
 	return fmt.Sprintf("unknown (%x)", c[:])
 }
 
+// Is matches the code against a StatusClass.
+func (c Code) Is(target error) bool {
+	if class, ok := target.(StatusClass); ok {
+		return c[0] == byte(class)
+	}
+
+	return false
+}
+
+// RetriesLeft returns the number of remaining retries
+// as indicated by SW2 of 63Cx status codes.
+// See: ISO 7816-4 Section 5.6 Status bytes
+func (c Code) RetriesLeft() (int, bool) {
+	if c[0] != 0x63 || c[1]&0xF0 != 0xC0 {
+		return 0, false
+	}
+
+	return int(c[1] & 0x0F), true
+}
+
+// IsWarning indicates that the command has been processed with a warning (62xx or 63xx).
+func (c Code) IsWarning() bool {
+	return c[0] == 0x62 || c[0] == 0x63
+}
+
 // HasMore indicates more data that needs to be fetched
 func (c Code) HasMore() bool {
 	return c[0] == 0x61
 }
 
+// HasWrongLe indicates that the command should be re-issued
+// with the exact length of available data as Le field.
+func (c Code) HasWrongLe() bool {
+	return c[0] == 0x6C
+}
+
+// Available returns the number of available data bytes
+// as indicated by SW2 of 61xx and 6Cxx status codes.
+func (c Code) Available() int {
+	if c[1] == 0x00 {
+		return MaxLenRespDataStandard
+	}
+
+	return int(c[1])
+}
+
 // IsSuccess indicates that all data has been successfully fetched
 func (c Code) IsSuccess() bool {
 	return c == ErrSuccess
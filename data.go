@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"fmt"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+// Data objects referencing data objects in the command data field of GET DATA and PUT DATA
+// See: ISO 7816-4 Section 8.4 Tag list, header list and extended header list
+const (
+	TagTagList            tlv.Tag = 0x5C // Tag list
+	TagHeaderList         tlv.Tag = 0x5D // Header list
+	TagExtendedHeaderList tlv.Tag = 0x4D // Extended header list
+)
+
+// GetData retrieves the data object with the given tag from the current DF.
+// One and two byte tags are encoded in P1-P2 of the even instruction.
+// Other tags are requested via a tag list using the odd instruction.
+// If the card returns only the value of the data object,
+// it is wrapped into a data object with the requested tag.
+// See: ISO 7816-4 Section 7.4.2 GET DATA command
+func (c *Card) GetData(tag tlv.Tag) (tlv.TagValues, error) {
+	return c.GetDataContext(context.Background(), tag)
+}
+
+// GetDataContext is like GetData but uses the provided context.
+func (c *Card) GetDataContext(ctx context.Context, tag tlv.Tag) (tlv.TagValues, error) {
+	p1, p2, ok := tagParams(tag)
+	if !ok {
+		tb, err := tag.MarshalBER()
+		if err != nil {
+			return nil, err
+		}
+
+		return c.GetDataListContext(ctx, tlv.New(TagTagList, tb))
+	}
+
+	resp, err := c.SendContext(ctx, &CAPDU{
+		Ins: InsGetData,
+		P1:  p1,
+		P2:  p2,
+		Ne:  c.maxLenRespData(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeDataObject(tag, resp)
+}
+
+// GetDataList retrieves the data objects referenced by a tag list,
+// header list or extended header list from the current DF using the odd instruction.
+// See: ISO 7816-4 Section 7.4.2 GET DATA command
+func (c *Card) GetDataList(list tlv.TagValue) (tlv.TagValues, error) {
+	return c.GetDataListContext(context.Background(), list)
+}
+
+// GetDataListContext is like GetDataList but uses the provided context.
+func (c *Card) GetDataListContext(ctx context.Context, list tlv.TagValue) (tlv.TagValues, error) {
+	return c.getDataList(ctx, FileIDCurrentDF, list)
+}
+
+// getDataList retrieves the data objects referenced by list from the file with the identifier fid.
+func (c *Card) getDataList(ctx context.Context, fid uint16, list tlv.TagValue) (tlv.TagValues, error) {
+	switch list.Tag {
+	case TagTagList, TagHeaderList, TagExtendedHeaderList:
+	default:
+		return nil, fmt.Errorf("%w: unsupported list tag %X", tlv.ErrInvalidTag, list.Tag)
+	}
+
+	data, err := tlv.EncodeBER(list)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.SendContext(ctx, &CAPDU{
+		Ins:  InsGetDataOdd,
+		P1:   byte(fid >> 8),
+		P2:   byte(fid & 0xFF),
+		Data: data,
+		Ne:   c.maxLenRespData(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tlv.DecodeBER(resp)
+}
+
+// PutData stores the data object in the current DF.
+// One and two byte tags are encoded in P1-P2 of the even instruction
+// and the command data field carries the value only.
+// Other data objects are sent in full using the odd instruction.
+// See: ISO 7816-4 Section 7.4.3 PUT DATA command
+func (c *Card) PutData(tv tlv.TagValue) error {
+	return c.PutDataContext(context.Background(), tv)
+}
+
+// PutDataContext is like PutData but uses the provided context.
+func (c *Card) PutDataContext(ctx context.Context, tv tlv.TagValue) (err error) {
+	cmd := &CAPDU{
+		Ins: InsPutDataOdd,
+		P1:  byte(FileIDCurrentDF >> 8),
+		P2:  byte(FileIDCurrentDF & 0xFF),
+	}
+
+	if p1, p2, ok := tagParams(tv.Tag); ok {
+		cmd.Ins, cmd.P1, cmd.P2 = InsPutData, p1, p2
+
+		if len(tv.Children) > 0 {
+			cmd.Data, err = tlv.EncodeBER(tv.Children...)
+		} else {
+			cmd.Data = tv.Value
+		}
+	} else {
+		cmd.Data, err = tlv.EncodeBER(tv)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, cmd)
+
+	return err
+}
+
+// tagParams encodes a BER-TLV tag into P1-P2 of the even GET DATA and PUT DATA instructions.
+// One byte tags are encoded as 00XX and two byte tags in the range 4000-FFFF as is.
+// See: ISO 7816-4 Section 7.4.1 Table 63
+func tagParams(tag tlv.Tag) (p1, p2 byte, ok bool) {
+	switch {
+	case tag <= 0xFF:
+		return 0x00, byte(tag), true
+
+	case tag >= 0x4000 && tag <= 0xFFFF:
+		return byte(tag >> 8), byte(tag), true
+
+	default:
+		return 0, 0, false
+	}
+}
+
+// decodeDataObject decodes the response of the even GET DATA instruction.
+// Cards either return the complete data object or only its value.
+func decodeDataObject(tag tlv.Tag, b []byte) (tlv.TagValues, error) {
+	if tvs, err := tlv.DecodeBER(b); err == nil && len(tvs) > 0 && tvs[0].Tag == tag {
+		return tvs, nil
+	}
+
+	tv := tlv.TagValue{
+		Tag:   tag,
+		Value: b,
+	}
+
+	if tag.IsConstructed() {
+		var err error
+		if tv.Children, err = tlv.DecodeBER(b); err != nil {
+			return nil, err
+		}
+	}
+
+	return tlv.TagValues{tv}, nil
+}
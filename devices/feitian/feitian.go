@@ -4,6 +4,7 @@
 package feitian
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"strings"
@@ -25,8 +26,13 @@ func NewCard(card iso.PCSCCard) *Card {
 // This is a custom version of iso7816.Card.Transmit()
 // as FEITIAN has a broken ISO-7816-4 implementation in their tokens.
 func (c *Card) Transmit(cmd *iso.CAPDU) ([]byte, error) {
+	return c.TransmitContext(context.Background(), cmd)
+}
+
+// TransmitContext is like Transmit but uses the provided context.
+func (c *Card) TransmitContext(ctx context.Context, cmd *iso.CAPDU) ([]byte, error) {
 	cmdBuf := []byte{
-		cmd.Cla,
+		byte(cmd.Cla),
 		byte(cmd.Ins),
 		cmd.P1,
 		cmd.P2,
@@ -44,7 +50,7 @@ func (c *Card) Transmit(cmd *iso.CAPDU) ([]byte, error) {
 
 	cmdBuf = append(cmdBuf, cmd.Data...)
 
-	return c.Card.Transmit(cmdBuf)
+	return c.Card.TransmitContext(ctx, cmdBuf)
 }
 
 func printable(b []byte) string {
@@ -57,8 +63,13 @@ func printable(b []byte) string {
 }
 
 func (c *Card) SerialNumber() (string, error) {
-	resp, err := c.Transmit(&iso.CAPDU{
-		Cla: 128,
+	return c.SerialNumberContext(context.Background())
+}
+
+// SerialNumberContext is like SerialNumber but uses the provided context.
+func (c *Card) SerialNumberContext(ctx context.Context) (string, error) {
+	resp, err := c.TransmitContext(ctx, &iso.CAPDU{
+		Cla: iso.ClassProprietary,
 		Ins: 227,
 		P1:  3,
 		P2:  0,
@@ -75,8 +86,13 @@ func (c *Card) SerialNumber() (string, error) {
 }
 
 func (c *Card) COSVersion() (string, error) {
-	if resp, err := c.Transmit(&iso.CAPDU{
-		Cla: 128,
+	return c.COSVersionContext(context.Background())
+}
+
+// COSVersionContext is like COSVersion but uses the provided context.
+func (c *Card) COSVersionContext(ctx context.Context) (string, error) {
+	if resp, err := c.TransmitContext(ctx, &iso.CAPDU{
+		Cla: iso.ClassProprietary,
 		Ins: 227,
 		P1:  0,
 		P2:  0,
@@ -90,8 +106,8 @@ func (c *Card) COSVersion() (string, error) {
 		}
 	}
 
-	if _, err := c.Transmit(&iso.CAPDU{
-		Cla: 0,
+	if _, err := c.TransmitContext(ctx, &iso.CAPDU{
+		Cla: iso.ClassInterindustry,
 		Ins: 164,
 		P1:  4,
 		P2:  0,
@@ -99,8 +115,8 @@ func (c *Card) COSVersion() (string, error) {
 		return "", err
 	}
 
-	if resp, err := c.Transmit(&iso.CAPDU{
-		Cla: 0,
+	if resp, err := c.TransmitContext(ctx, &iso.CAPDU{
+		Cla: iso.ClassInterindustry,
 		Ins: 202,
 		P1:  159,
 		P2:  127,
@@ -5,6 +5,7 @@
 package nitrokey
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -93,7 +94,12 @@ func NewCard(card iso.PCSCCard) *Card {
 
 // DeviceStatus returns the device status of the Nitrokey 3 token.
 func (c *Card) DeviceStatus() (*DeviceStatus, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.DeviceStatusContext(context.Background())
+}
+
+// DeviceStatusContext is like DeviceStatus but uses the provided context.
+func (c *Card) DeviceStatusContext(ctx context.Context) (*DeviceStatus, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins:  iso.Instruction(InsAdminStatus),
 		P1:   0x00,
 		P2:   0x00,
@@ -114,7 +120,12 @@ func (c *Card) DeviceStatus() (*DeviceStatus, error) {
 
 // UUID returns the UUID of the Nitrokey 3 token.
 func (c *Card) UUID() ([]byte, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.UUIDContext(context.Background())
+}
+
+// UUIDContext is like UUID but uses the provided context.
+func (c *Card) UUIDContext(ctx context.Context) ([]byte, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsGetUUID,
 		P1:  0x00,
 		P2:  0x00,
@@ -133,7 +144,12 @@ func (c *Card) UUID() ([]byte, error) {
 
 // FirmwareVersion returns the firmware version of the Nitrokey 3 token.
 func (c *Card) FirmwareVersion() (*iso.Version, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.FirmwareVersionContext(context.Background())
+}
+
+// FirmwareVersionContext is like FirmwareVersion but uses the provided context.
+func (c *Card) FirmwareVersionContext(ctx context.Context) (*iso.Version, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins:  InsGetFirmwareVersion,
 		P1:   0x00,
 		P2:   0x00,
@@ -161,7 +177,12 @@ func (c *Card) FirmwareVersion() (*iso.Version, error) {
 
 // Random returns LenRandom bytes of random bytes.
 func (c *Card) Random() ([]byte, error) {
-	return c.Send(&iso.CAPDU{
+	return c.RandomContext(context.Background())
+}
+
+// RandomContext is like Random but uses the provided context.
+func (c *Card) RandomContext(ctx context.Context) ([]byte, error) {
+	return c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsRNG,
 		Ne:  LenRandom,
 	})
@@ -169,7 +190,12 @@ func (c *Card) Random() ([]byte, error) {
 
 // Reboot resets the token.
 func (c *Card) Reboot() error {
-	_, err := c.Send(&iso.CAPDU{Ins: InsReboot})
+	return c.RebootContext(context.Background())
+}
+
+// RebootContext is like Reboot but uses the provided context.
+func (c *Card) RebootContext(ctx context.Context) error {
+	_, err := c.SendContext(ctx, &iso.CAPDU{Ins: InsReboot})
 	if !errors.Is(err, scard.ErrReaderUnavailable) {
 		return fmt.Errorf("unexpected error: %w", err)
 	}
@@ -178,7 +204,7 @@ func (c *Card) Reboot() error {
 	// requires re-enumerating/reconnecting.
 	if pcscCard := c.Base(); pcscCard != nil {
 		if rcard, ok := pcscCard.(iso.ReconnectableCard); ok {
-			if err := rcard.Reconnect(false); err != nil {
+			if err := rcard.ReconnectContext(ctx, false); err != nil {
 				return err
 			}
 		}
@@ -190,7 +216,12 @@ func (c *Card) Reboot() error {
 // IsLocked checks if the bootloader is locked.
 // Locked bootloaders can only be updated via officially signed firmware images.
 func (c *Card) IsLocked() (bool, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.IsLockedContext(context.Background())
+}
+
+// IsLockedContext is like IsLocked but uses the provided context.
+func (c *Card) IsLockedContext(ctx context.Context) (bool, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsLocked,
 		Ne:  1,
 	})
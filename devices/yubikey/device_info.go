@@ -4,6 +4,7 @@
 package yubikey
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"time"
@@ -186,7 +187,12 @@ func (di *DeviceInfo) Unmarshal(b []byte) error {
 
 // GetDeviceInfo returns device information about the YubiKey token.
 func (c *Card) DeviceInfo() (*DeviceInfo, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.DeviceInfoContext(context.Background())
+}
+
+// DeviceInfoContext is like DeviceInfo but uses the provided context.
+func (c *Card) DeviceInfoContext(ctx context.Context) (*DeviceInfo, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: 0x1D,
 		P1:  0x00,
 		P2:  0x00,
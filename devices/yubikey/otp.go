@@ -4,6 +4,7 @@
 package yubikey
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 
@@ -34,7 +35,12 @@ func (s *Status) Unmarshal(b []byte) error {
 
 // Status returns the status of the YubiKey token.
 func (c *Card) Status() (*Status, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but uses the provided context.
+func (c *Card) StatusContext(ctx context.Context) (*Status, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsReadStatus,
 		P1:  0x00,
 		P2:  0x00,
@@ -53,7 +59,12 @@ func (c *Card) Status() (*Status, error) {
 
 // SerialNumber returns the serial number of the YubiKey token.
 func (c *Card) SerialNumber() (uint32, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.SerialNumberContext(context.Background())
+}
+
+// SerialNumberContext is like SerialNumber but uses the provided context.
+func (c *Card) SerialNumberContext(ctx context.Context) (uint32, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsOTP,
 		P1:  0x10,
 		P2:  0x00,
@@ -71,7 +82,12 @@ func (c *Card) SerialNumber() (uint32, error) {
 
 // FIPSMode returns returns the FIPS compliancy state of the YubiKey token.
 func (c *Card) FIPSMode() (bool, error) {
-	resp, err := c.Send(&iso.CAPDU{
+	return c.FIPSModeContext(context.Background())
+}
+
+// FIPSModeContext is like FIPSMode but uses the provided context.
+func (c *Card) FIPSModeContext(ctx context.Context) (bool, error) {
+	resp, err := c.SendContext(ctx, &iso.CAPDU{
 		Ins: InsOTP,
 		P1:  0x14,
 		P2:  0x00,
@@ -0,0 +1,248 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxOccurrences limits the number of applications enumerated
+// by SELECT next occurrence for a single partial DF name.
+// This guards against cards which ignore the occurrence in P2.
+const maxOccurrences = 64
+
+// DiscoveryMethod is a bitmask of methods used by Card.Discover().
+type DiscoveryMethod byte
+
+const (
+	// DiscoverByDIR selects the applications listed in EF.DIR.
+	DiscoverByDIR DiscoveryMethod = (1 << iota)
+
+	// DiscoverByPartialAID enumerates all applications below the RIDs
+	// of the known applets by SELECT by partial DF name with next occurrence.
+	DiscoverByPartialAID
+
+	// DiscoverByKnownAIDs probes each of the KnownApplets by SELECT by full DF name.
+	DiscoverByKnownAIDs
+
+	DiscoverAll = DiscoverByDIR | DiscoverByPartialAID | DiscoverByKnownAIDs
+)
+
+// DiscoveredApplet is an applet found by Card.Discover().
+type DiscoveredApplet struct {
+	AID AID
+
+	// Name is the name of the applet in KnownApplets or empty if unknown.
+	Name string
+
+	// Method contains all methods which found the applet.
+	Method DiscoveryMethod
+
+	// Response is the response data of the SELECT command.
+	// It is empty if the applet is listed in EF.DIR but could not be selected.
+	Response []byte
+}
+
+// Discover returns the applets present on the card using the given methods.
+// Methods which are not supported according to the card service data or card
+// capabilities of the historical bytes are skipped. All methods are attempted
+// if the card does not indicate either of them.
+// A failing SELECT is interpreted as an absent applet while other errors
+// like transmission failures abort the discovery.
+// Note that the discovery changes the currently selected application.
+func (c *Card) Discover(methods DiscoveryMethod) ([]DiscoveredApplet, error) {
+	return c.DiscoverContext(context.Background(), methods)
+}
+
+// DiscoverContext is like Discover but uses the provided context.
+func (c *Card) DiscoverContext(ctx context.Context, methods DiscoveryMethod) ([]DiscoveredApplet, error) {
+	d := &discovery{
+		card:  c,
+		index: map[string]int{},
+	}
+
+	methods = c.supportedDiscoveryMethods(methods)
+
+	if methods&DiscoverByDIR != 0 {
+		if err := d.byDIR(ctx); err != nil {
+			return nil, fmt.Errorf("failed to discover applets by EF.DIR: %w", err)
+		}
+	}
+
+	if methods&DiscoverByPartialAID != 0 {
+		if err := d.byPartialAID(ctx); err != nil {
+			return nil, fmt.Errorf("failed to discover applets by partial AID: %w", err)
+		}
+	}
+
+	if methods&DiscoverByKnownAIDs != 0 {
+		if err := d.byKnownAIDs(ctx); err != nil {
+			return nil, fmt.Errorf("failed to discover applets by known AIDs: %w", err)
+		}
+	}
+
+	return d.applets, nil
+}
+
+// supportedDiscoveryMethods removes methods which are not supported by the card
+// according to the card service data and card capabilities of the historical bytes.
+// See: ISO 7816-4 Section 8.1.1.2.3 Card service data
+// See: ISO 7816-4 Section 8.1.1.2.7 Card capabilities
+func (c *Card) supportedDiscoveryMethods(methods DiscoveryMethod) DiscoveryMethod {
+	hb := c.historicalBytes()
+	if hb == nil {
+		return methods
+	}
+
+	cs, cc := hb.CardService, hb.CardCapabilities
+
+	if cs != 0 && cs&CardServiceBERTLVInDIR == 0 {
+		methods &^= DiscoverByDIR
+	}
+
+	if (cs != 0 || cc != 0) && cs&CardServiceAppSelectionPartialDF == 0 && cc&CardCapDFSelectByPartialDFName == 0 {
+		methods &^= DiscoverByPartialAID
+	}
+
+	if (cs != 0 || cc != 0) && cs&CardServiceAppSelectionFullDF == 0 && cc&CardCapDFSelectByFullDFName == 0 {
+		methods &^= DiscoverByKnownAIDs
+	}
+
+	return methods
+}
+
+type discovery struct {
+	card    *Card
+	applets []DiscoveredApplet
+	index   map[string]int
+}
+
+// add records an applet or merges it with a previously discovered one.
+func (d *discovery) add(aid AID, method DiscoveryMethod, resp []byte) {
+	if i, ok := d.index[string(aid)]; ok {
+		a := &d.applets[i]
+		a.Method |= method
+		if a.Response == nil {
+			a.Response = resp
+		}
+
+		return
+	}
+
+	app, _ := aid.Applet()
+
+	d.index[string(aid)] = len(d.applets)
+	d.applets = append(d.applets, DiscoveredApplet{
+		AID:      aid,
+		Name:     app.Name,
+		Method:   method,
+		Response: resp,
+	})
+}
+
+func (d *discovery) byDIR(ctx context.Context) error {
+	apps, err := d.card.ApplicationsContext(ctx)
+	if err != nil {
+		if isStatusError(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	for _, app := range apps {
+		if len(app.AID) == 0 {
+			continue
+		}
+
+		resp, err := d.card.SelectContext(ctx, app.AID)
+		if err != nil && !isStatusError(err) {
+			return err
+		}
+
+		d.add(app.AID, DiscoverByDIR, resp)
+	}
+
+	return nil
+}
+
+// byPartialAID enumerates the applications below each RID of the known applets.
+// See: ISO 7816-4 Section 7.1.1 SELECT command
+func (d *discovery) byPartialAID(ctx context.Context) error {
+	for _, rid := range knownRIDs() {
+		seen := map[string]bool{}
+
+		for i, occ := 0, SelectFirst; i < maxOccurrences; i, occ = i+1, SelectNext {
+			resp, err := d.card.SendContext(ctx, &CAPDU{
+				Ins:  InsSelect,
+				P1:   byte(SelectByDFName),
+				P2:   byte(SelectReturnFCI) | byte(occ),
+				Data: rid,
+				Ne:   d.card.maxLenRespData(),
+			})
+			if err != nil {
+				if isStatusError(err) {
+					break
+				}
+
+				return err
+			}
+
+			// Without the DF name in the FCI, we can not tell
+			// which application has been selected.
+			fci, err := ParseFileControlParameters(resp)
+			if err != nil || len(fci.DFName) == 0 || seen[string(fci.DFName)] {
+				break
+			}
+
+			seen[string(fci.DFName)] = true
+
+			d.add(fci.DFName, DiscoverByPartialAID, resp)
+		}
+	}
+
+	return nil
+}
+
+func (d *discovery) byKnownAIDs(ctx context.Context) error {
+	for _, app := range KnownApplets {
+		resp, err := d.card.SelectContext(ctx, app.AID)
+		if err != nil {
+			if isStatusError(err) {
+				continue
+			}
+
+			return err
+		}
+
+		d.add(app.AID, DiscoverByKnownAIDs, resp)
+	}
+
+	return nil
+}
+
+// knownRIDs returns the unique registered application provider
+// identifiers of the KnownApplets in their order of appearance.
+func knownRIDs() (rids [][]byte) {
+	seen := map[RID]bool{}
+
+	for _, app := range KnownApplets {
+		rid, ok := app.AID.RID()
+		if !ok || seen[rid] {
+			continue
+		}
+
+		seen[rid] = true
+		rids = append(rids, rid[:])
+	}
+
+	return rids
+}
+
+func isStatusError(err error) bool {
+	var serr *StatusError
+	return errors.As(err, &serr)
+}
@@ -4,6 +4,7 @@
 package pcsc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -17,6 +18,9 @@ var (
 	_ iso.ReconnectableCard = (*Card)(nil)
 	_ iso.ReaderCard        = (*Card)(nil)
 	_ iso.PCSCCard          = (*Card)(nil)
+	_ iso.ContextCard       = (*Card)(nil)
+	_ iso.ATRCard           = (*Card)(nil)
+	_ iso.LockingCard       = (*Card)(nil)
 )
 
 // Card implements the iso7816.PCSCCard interface
@@ -27,6 +31,7 @@ type Card struct {
 	ctx    *scard.Context
 	reader string
 	mode   scard.ShareMode
+	lock   iso.CardLock
 }
 
 // NewCard creates a new card by connecting via the PC/SC API.
@@ -41,7 +46,12 @@ func NewCard(ctx *scard.Context, reader string, shared bool) (*iso.Card, error)
 		return nil, fmt.Errorf("failed to connect to reader: %w", err)
 	}
 
-	return iso.NewCard(&Card{sc, ctx, reader, mode}), nil
+	return iso.NewCard(&Card{
+		Card:   sc,
+		ctx:    ctx,
+		reader: reader,
+		mode:   mode,
+	}), nil
 }
 
 func (c *Card) Base() iso.PCSCCard {
@@ -53,11 +63,30 @@ func (c *Card) Transmit(cmd []byte) ([]byte, error) {
 	return c.Card.Transmit(cmd)
 }
 
+// TransmitContext wraps SCardTransmit.
+// The context is only checked before the command is transmitted as
+// an in-flight SCardTransmit can not be interrupted.
+func (c *Card) TransmitContext(ctx context.Context, cmd []byte) (resp []byte, err error) {
+	err = call(ctx, func() (err error) {
+		resp, err = c.Card.Transmit(cmd)
+		return err
+	})
+
+	return resp, err
+}
+
 // BeginTransaction wraps SCardBeginTransaction.
 func (c *Card) BeginTransaction() error {
 	return c.Card.BeginTransaction()
 }
 
+// BeginTransactionContext wraps SCardBeginTransaction.
+// Like TransmitContext, the context is only checked before the call
+// as waiting for the transaction can not be interrupted.
+func (c *Card) BeginTransactionContext(ctx context.Context) error {
+	return call(ctx, c.Card.BeginTransaction)
+}
+
 // EndTransaction wraps SCardEndTransaction.
 func (c *Card) EndTransaction() error {
 	return c.Card.EndTransaction(scard.LeaveCard)
@@ -68,23 +97,72 @@ func (c *Card) Close() error {
 	return c.Disconnect(scard.ResetCard)
 }
 
-// Reset reconnects and resets the card
+// Reconnect reconnects and optionally resets the card
 func (c *Card) Reconnect(reset bool) (err error) {
+	return c.ReconnectContext(context.Background(), reset)
+}
+
+// ReconnectContext is like Reconnect but aborts waiting for the
+// card to re-appear once the context is canceled.
+// A pending reset of the card can not be interrupted.
+func (c *Card) ReconnectContext(ctx context.Context, reset bool) (err error) {
 	if reset {
-		return c.Card.Reconnect(scard.ShareShared, scard.ProtocolT1, scard.ResetCard)
+		return call(ctx, func() error {
+			return c.Card.Reconnect(scard.ShareShared, scard.ProtocolT1, scard.ResetCard)
+		})
 	}
 
 	for {
 		if c.Card, err = c.ctx.Connect(c.reader, c.mode, scard.ProtocolAny); err == nil {
 			return nil
 		} else if errors.Is(err, scard.ErrUnknownReader) || errors.Is(err, scard.ErrNoSmartcard) {
-			time.Sleep(100 * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+			}
 		} else {
 			return err
 		}
 	}
 }
 
+// call invokes a blocking PC/SC function unless the context is already canceled.
+// SCardCancel only interrupts SCardGetStatusChange of the whole PC/SC context
+// but not an in-flight SCardTransmit, SCardBeginTransaction or SCardReconnect.
+// Hence, the deadline of the context is not enforced while fn is pending.
+// If the context has been canceled meanwhile and fn failed, both errors are returned.
+func call(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("%w: %w", ctxErr, err)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// ATR returns the answer-to-reset of the card.
+func (c *Card) ATR() (*iso.ATR, error) {
+	sts, err := c.Status()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	return iso.ParseATR(sts.Atr)
+}
+
+// CardLock returns the lock shared by all iso7816.Cards wrapping this card.
+func (c *Card) CardLock() *iso.CardLock {
+	return &c.lock
+}
+
 // Reader returns the name of the reader.
 func (c *Card) Reader() string {
 	return c.reader
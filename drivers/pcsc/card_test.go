@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package pcsc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ebfe/scard"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallCanceled(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := call(ctx, func() error {
+		called = true
+		return nil
+	})
+	require.ErrorIs(err, context.Canceled)
+	require.False(called)
+}
+
+func TestCallCanceledWhileBlocking(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// The blocking call is not interrupted and its error is retained
+	err := call(ctx, func() error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return scard.ErrReaderUnavailable
+	})
+	require.ErrorIs(err, context.DeadlineExceeded)
+	require.ErrorIs(err, scard.ErrReaderUnavailable)
+
+	// A call which succeeded despite the cancellation is retained
+	ctx, cancel = context.WithCancel(context.Background())
+
+	err = call(ctx, func() error {
+		cancel()
+		return nil
+	})
+	require.NoError(err)
+}
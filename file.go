@@ -0,0 +1,341 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+var ErrInvalidFileControlParameters = errors.New("invalid file control parameters")
+
+const (
+	FileIDMF        uint16 = 0x3F00 // File identifier of the master file (MF)
+	FileIDCurrentDF uint16 = 0x3FFF // References the current DF in P1-P2 of data object handling commands
+)
+
+// SelectMode is the selection mode encoded in P1 of the SELECT command.
+// See: ISO 7816-4 Section 7.1.1 Table 39
+type SelectMode byte
+
+const (
+	SelectMFDFOrEF   SelectMode = 0x00 // Select MF, DF or EF by file identifier. The MF is selected if the data field is absent or '3F00'
+	SelectChildDF    SelectMode = 0x01 // Select child DF by file identifier
+	SelectEFUnderDF  SelectMode = 0x02 // Select EF under current DF by file identifier
+	SelectParentDF   SelectMode = 0x03 // Select parent DF of the current DF
+	SelectByDFName   SelectMode = 0x04 // Select by DF name
+	SelectPathFromMF SelectMode = 0x08 // Select from the MF by path without the identifier of the MF
+	SelectPathFromDF SelectMode = 0x09 // Select from the current DF by path without the identifier of the current DF
+)
+
+// SelectOccurrence is the file occurrence encoded in P2 of the SELECT command.
+// See: ISO 7816-4 Section 7.1.1 Table 40
+type SelectOccurrence byte
+
+const (
+	SelectFirst    SelectOccurrence = 0x00 // First or only occurrence
+	SelectLast     SelectOccurrence = 0x01 // Last occurrence
+	SelectNext     SelectOccurrence = 0x02 // Next occurrence
+	SelectPrevious SelectOccurrence = 0x03 // Previous occurrence
+)
+
+// SelectResponse is the requested response encoded in P2 of the SELECT command.
+// See: ISO 7816-4 Section 7.1.1 Table 40
+type SelectResponse byte
+
+const (
+	SelectReturnFCI  SelectResponse = 0x00 // Return FCI template, optional use of FCI tag and length
+	SelectReturnFCP  SelectResponse = 0x04 // Return FCP template, mandatory use of FCP tag and length
+	SelectReturnFMD  SelectResponse = 0x08 // Return FMD template, mandatory use of FMD tag and length
+	SelectReturnNone SelectResponse = 0x0C // No response data if Le field absent, or proprietary if Le field present
+)
+
+// FileSelection describes the parameters of a SELECT command.
+type FileSelection struct {
+	Mode       SelectMode
+	Occurrence SelectOccurrence
+	Response   SelectResponse
+
+	// ID is the file identifier, path or DF name depending on the selection mode.
+	ID []byte
+}
+
+// FileID encodes one or more file identifiers as they are used in
+// the data field of the SELECT command.
+func FileID(fids ...uint16) (id []byte) {
+	for _, fid := range fids {
+		id = append(id, byte(fid>>8), byte(fid))
+	}
+
+	return id
+}
+
+// SelectFile selects a file and returns its parsed control parameters.
+// The returned parameters are nil if no response data has been requested.
+func (c *Card) SelectFile(sel FileSelection) (*FileControlParameters, error) {
+	return c.SelectFileContext(context.Background(), sel)
+}
+
+// SelectFileContext is like SelectFile but uses the provided context.
+func (c *Card) SelectFileContext(ctx context.Context, sel FileSelection) (*FileControlParameters, error) {
+	cmd := &CAPDU{
+		Ins:  InsSelect,
+		P1:   byte(sel.Mode),
+		P2:   byte(sel.Response) | byte(sel.Occurrence),
+		Data: sel.ID,
+	}
+
+	if sel.Response != SelectReturnNone {
+		cmd.Ne = c.maxLenRespData()
+	}
+
+	resp, err := c.SendContext(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if sel.Response == SelectReturnNone || len(resp) == 0 {
+		return nil, nil //nolint:nilnil
+	}
+
+	return ParseFileControlParameters(resp)
+}
+
+// Tags of file control parameters
+// See: ISO 7816-4 Section 5.3.3 File control information
+const (
+	TagFCP tlv.Tag = 0x62 // File control parameters template
+	TagFMD tlv.Tag = 0x64 // File management data template
+	TagFCI tlv.Tag = 0x6F // File control information template
+
+	TagFileSize                        tlv.Tag = 0x80 // Number of data bytes in the file, excluding structural information
+	TagFileTotalSize                   tlv.Tag = 0x81 // Number of data bytes in the file, including structural information if any
+	TagFileDescriptor                  tlv.Tag = 0x82 // File descriptor byte, data coding byte, maximum record size and number of records
+	TagFileID                          tlv.Tag = 0x83 // File identifier
+	TagDFName                          tlv.Tag = 0x84 // DF name
+	TagFileProprietary                 tlv.Tag = 0x85 // Proprietary information not encoded in BER-TLV
+	TagSecurityAttrProprietary         tlv.Tag = 0x86 // Security attribute in proprietary format
+	TagFCIExtension                    tlv.Tag = 0x87 // Identifier of an EF containing an extension of the file control information
+	TagShortFileID                     tlv.Tag = 0x88 // Short EF identifier
+	TagLifeCycleStatus                 tlv.Tag = 0x8A // Life cycle status byte
+	TagSecurityAttrExpanded            tlv.Tag = 0x8B // Security attribute referencing the expanded format
+	TagSecurityAttrCompact             tlv.Tag = 0x8C // Security attribute in compact format
+	TagSecurityEnvironmentEF           tlv.Tag = 0x8D // Identifier of an EF containing security environment templates
+	TagChannelSecurity                 tlv.Tag = 0x8E // Channel security attribute
+	TagSecurityAttrDataObjects         tlv.Tag = 0xA0 // Security attribute template for data objects
+	TagSecurityAttrProprietaryTemplate tlv.Tag = 0xA1 // Security attribute template in proprietary format
+	TagFileProprietaryTemplate         tlv.Tag = 0xA5 // Proprietary information encoded in BER-TLV
+	TagSecurityAttrExpandedTemplate    tlv.Tag = 0xAB // Security attribute template in expanded format
+)
+
+// FileDescriptor is the file descriptor byte.
+// See: ISO 7816-4 Section 5.3.3 Table 12
+type FileDescriptor byte
+
+// EFStructure is the structure of an elementary file (EF).
+type EFStructure byte
+
+const (
+	EFStructureNone              EFStructure = 0x00 // No information given
+	EFStructureTransparent       EFStructure = 0x01 // Transparent structure
+	EFStructureLinearFixed       EFStructure = 0x02 // Linear structure, fixed size, no further information
+	EFStructureLinearFixedTLV    EFStructure = 0x03 // Linear structure, fixed size, TLV structure
+	EFStructureLinearVariable    EFStructure = 0x04 // Linear structure, variable size, no further information
+	EFStructureLinearVariableTLV EFStructure = 0x05 // Linear structure, variable size, TLV structure
+	EFStructureCyclic            EFStructure = 0x06 // Cyclic structure, fixed size, no further information
+	EFStructureCyclicTLV         EFStructure = 0x07 // Cyclic structure, fixed size, TLV structure
+)
+
+const (
+	fileDescriptorShareable        = 0x40
+	fileDescriptorCategoryMask     = 0x38
+	fileDescriptorCategoryWorking  = 0x00
+	fileDescriptorCategoryInternal = 0x08
+	fileDescriptorCategoryDF       = 0x38
+)
+
+// IsDF indicates that the file is a dedicated file (DF).
+func (d FileDescriptor) IsDF() bool {
+	return byte(d)&fileDescriptorCategoryMask == fileDescriptorCategoryDF
+}
+
+// IsWorkingEF indicates that the file is a working elementary file.
+func (d FileDescriptor) IsWorkingEF() bool {
+	return byte(d)&fileDescriptorCategoryMask == fileDescriptorCategoryWorking
+}
+
+// IsInternalEF indicates that the file is an internal elementary file.
+func (d FileDescriptor) IsInternalEF() bool {
+	return byte(d)&fileDescriptorCategoryMask == fileDescriptorCategoryInternal
+}
+
+// IsShareable indicates that the file supports shared access.
+func (d FileDescriptor) IsShareable() bool {
+	return byte(d)&fileDescriptorShareable != 0
+}
+
+// Structure returns the structure of an elementary file.
+func (d FileDescriptor) Structure() EFStructure {
+	if d.IsDF() {
+		return EFStructureNone
+	}
+
+	return EFStructure(byte(d) & 0x07)
+}
+
+// IsRecordStructure indicates that the elementary file is record-oriented.
+func (s EFStructure) IsRecordStructure() bool {
+	return s >= EFStructureLinearFixed
+}
+
+// FileControlParameters are the file control parameters (FCP),
+// file management data (FMD) or file control information (FCI)
+// returned when selecting a file.
+// See: ISO 7816-4 Section 5.3.3 File control information
+type FileControlParameters struct {
+	// Template is the tag of the template containing the parameters.
+	Template tlv.Tag
+
+	Size          int            // Number of data bytes in the file, excluding structural information
+	TotalSize     int            // Number of data bytes in the file, including structural information
+	Descriptor    FileDescriptor // File descriptor byte
+	DataCoding    byte           // Data coding byte
+	MaxRecordSize int            // Maximum record size of record-oriented EFs
+	NumRecords    int            // Number of records of record-oriented EFs
+
+	FileID          uint16 // File identifier
+	DFName          []byte // DF name
+	ShortFileID     byte   // Short EF identifier or zero if not indicated
+	LifeCycleStatus byte   // Life cycle status byte
+
+	// Proprietary contains the proprietary information not encoded in BER-TLV (tag '85').
+	Proprietary []byte
+
+	// ProprietaryTemplate contains the proprietary information encoded in BER-TLV (tag 'A5').
+	ProprietaryTemplate tlv.TagValues
+
+	// SecurityAttributes contains the security attribute data objects
+	// (tags '86', '8B', '8C', '8D', '8E', 'A0', 'A1' and 'AB').
+	SecurityAttributes tlv.TagValues
+
+	// TagValues contains all data objects of the template.
+	TagValues tlv.TagValues
+}
+
+// ParseFileControlParameters parses the response data of a SELECT command.
+// The data must contain a FCP, FMD or FCI template.
+// FCP and FMD templates nested in a FCI template are merged.
+func ParseFileControlParameters(b []byte) (*FileControlParameters, error) {
+	tvs, err := tlv.DecodeBER(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFileControlParameters, err)
+	}
+
+	if len(tvs) != 1 {
+		return nil, fmt.Errorf("%w: expected a single template, got %d data objects", ErrInvalidFileControlParameters, len(tvs))
+	}
+
+	fcp := &FileControlParameters{
+		Template: tvs[0].Tag,
+	}
+
+	switch fcp.Template {
+	case TagFCP, TagFMD, TagFCI:
+	default:
+		return nil, fmt.Errorf("%w: unexpected template %x", ErrInvalidFileControlParameters, fcp.Template)
+	}
+
+	if err := fcp.decode(tvs[0].Children); err != nil {
+		return nil, err
+	}
+
+	return fcp, nil
+}
+
+func (fcp *FileControlParameters) decode(tvs tlv.TagValues) error {
+	for _, tv := range tvs {
+		fcp.TagValues = append(fcp.TagValues, tv)
+
+		switch tv.Tag {
+		case TagFCP, TagFMD:
+			if err := fcp.decode(tv.Children); err != nil {
+				return err
+			}
+
+		case TagFileSize:
+			fcp.Size = decodeUint(tv.Value)
+
+		case TagFileTotalSize:
+			fcp.TotalSize = decodeUint(tv.Value)
+
+		case TagFileDescriptor:
+			if len(tv.Value) < 1 || len(tv.Value) > 6 {
+				return fmt.Errorf("%w: file descriptor", ErrInvalidFileControlParameters)
+			}
+
+			fcp.Descriptor = FileDescriptor(tv.Value[0])
+
+			if len(tv.Value) > 1 {
+				fcp.DataCoding = tv.Value[1]
+			}
+
+			// The maximum record size is encoded in one or two bytes
+			if len(tv.Value) > 2 {
+				fcp.MaxRecordSize = decodeUint(tv.Value[2:min(len(tv.Value), 4)])
+			}
+
+			if len(tv.Value) > 4 {
+				fcp.NumRecords = decodeUint(tv.Value[4:])
+			}
+
+		case TagFileID:
+			if len(tv.Value) != 2 {
+				return fmt.Errorf("%w: file identifier", ErrInvalidFileControlParameters)
+			}
+
+			fcp.FileID = uint16(decodeUint(tv.Value))
+
+		case TagDFName:
+			fcp.DFName = tv.Value
+
+		case TagFileProprietary:
+			fcp.Proprietary = tv.Value
+
+		case TagFileProprietaryTemplate:
+			fcp.ProprietaryTemplate = tv.Children
+
+		case TagShortFileID:
+			// An empty data object indicates that short EF identifiers are not supported
+			if len(tv.Value) == 1 {
+				fcp.ShortFileID = tv.Value[0] >> 3
+			}
+
+		case TagLifeCycleStatus:
+			if len(tv.Value) != 1 {
+				return fmt.Errorf("%w: life cycle status", ErrInvalidFileControlParameters)
+			}
+
+			fcp.LifeCycleStatus = tv.Value[0]
+
+		case TagSecurityAttrProprietary, TagSecurityAttrExpanded, TagSecurityAttrCompact,
+			TagSecurityEnvironmentEF, TagChannelSecurity, TagSecurityAttrDataObjects,
+			TagSecurityAttrProprietaryTemplate, TagSecurityAttrExpandedTemplate:
+			fcp.SecurityAttributes = append(fcp.SecurityAttributes, tv)
+		}
+	}
+
+	return nil
+}
+
+// decodeUint decodes a big-endian unsigned integer.
+func decodeUint(b []byte) (n int) {
+	for _, c := range b {
+		n = n<<8 | int(c)
+	}
+
+	return n
+}
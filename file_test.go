@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+func TestParseFileControlParameters(t *testing.T) {
+	require := require.New(t)
+
+	fcp, err := iso.ParseFileControlParameters(unhex("6216820101" + "83022f00" + "80020020" + "8801f0" + "8a0105" + "8c0303c000"))
+	require.NoError(err)
+
+	require.Equal(iso.TagFCP, fcp.Template)
+	require.Equal(uint16(0x2F00), fcp.FileID)
+	require.Equal(32, fcp.Size)
+	require.Equal(byte(30), fcp.ShortFileID)
+	require.Equal(byte(0x05), fcp.LifeCycleStatus)
+	require.False(fcp.Descriptor.IsDF())
+	require.True(fcp.Descriptor.IsWorkingEF())
+	require.Equal(iso.EFStructureTransparent, fcp.Descriptor.Structure())
+	require.Len(fcp.SecurityAttributes, 1)
+	require.Equal(tlv.Tag(0x8C), fcp.SecurityAttributes[0].Tag)
+	require.Len(fcp.TagValues, 6)
+}
+
+func TestParseFileControlParametersRecords(t *testing.T) {
+	require := require.New(t)
+
+	fcp, err := iso.ParseFileControlParameters(unhex("620b" + "82050221001a0a" + "83026f3a"))
+	require.NoError(err)
+
+	require.Equal(iso.EFStructureLinearFixed, fcp.Descriptor.Structure())
+	require.True(fcp.Descriptor.Structure().IsRecordStructure())
+	require.Equal(byte(0x21), fcp.DataCoding)
+	require.Equal(26, fcp.MaxRecordSize)
+	require.Equal(10, fcp.NumRecords)
+
+	// Maximum record size in a single byte without number of records
+	fcp, err = iso.ParseFileControlParameters(unhex("6205" + "8203042120"))
+	require.NoError(err)
+
+	require.Equal(iso.EFStructureLinearVariable, fcp.Descriptor.Structure())
+	require.Equal(byte(0x21), fcp.DataCoding)
+	require.Equal(32, fcp.MaxRecordSize)
+	require.Zero(fcp.NumRecords)
+}
+
+func TestParseFileControlInformation(t *testing.T) {
+	require := require.New(t)
+
+	fci, err := iso.ParseFileControlParameters(unhex("6f13" + "8407a0000002471001" + "6203820138" + "a503500141"))
+	require.NoError(err)
+
+	require.Equal(iso.TagFCI, fci.Template)
+	require.Equal(unhex("a0000002471001"), fci.DFName)
+	require.True(fci.Descriptor.IsDF())
+
+	label, _, ok := fci.ProprietaryTemplate.Get(0x50)
+	require.True(ok)
+	require.Equal([]byte("A"), label)
+}
+
+func TestParseFileControlParametersError(t *testing.T) {
+	for n, s := range map[string]string{
+		"template":   "700182",
+		"multiple":   "62006200",
+		"file id":    "6203830100",
+		"descriptor": "62028200",
+		"truncated":  "620582",
+	} {
+		t.Run(n, func(t *testing.T) {
+			_, err := iso.ParseFileControlParameters(unhex(s))
+			require.ErrorIs(t, err, iso.ErrInvalidFileControlParameters)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform_test
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+const (
+	insEcho     iso.Instruction = 0xEE // Returns the command data
+	insGenerate iso.Instruction = 0xEF // Returns P1 || P2 bytes of data
+)
+
+var _ iso.PCSCCard = (*simCard)(nil)
+
+// securityDomain is the card-side implementation of a secure channel protocol.
+type securityDomain interface {
+	// process handles a command APDU and returns the (protected) response.
+	// The serialized command is passed to authenticate the length fields.
+	process(cmd *iso.CAPDU, cmdBuf []byte) *iso.RAPDU
+}
+
+// simCard simulates a smart card with a single security domain.
+// It handles the retrieval of long responses via GET RESPONSE.
+type simCard struct {
+	sd       securityDomain
+	pending  []byte
+	channels []int
+}
+
+func (c *simCard) Transmit(cmdBuf []byte) ([]byte, error) {
+	cmd, err := iso.ParseCAPDU(cmdBuf)
+	if err != nil {
+		return iso.ErrWrongLength[:], nil //nolint:nilerr
+	}
+
+	c.channels = append(c.channels, cmd.Cla.Channel())
+
+	if cmd.Ins == iso.InsManageChannel && cmd.P1 == 0x00 {
+		return c.respond([]byte{0x01}, iso.ErrSuccess), nil
+	}
+
+	if cmd.Ins == iso.InsGetResponse && cmd.Cla.SecureMessaging() == iso.SecureMessagingNone {
+		return c.respond(c.pending, iso.ErrSuccess), nil
+	}
+
+	resp := c.sd.process(cmd, cmdBuf)
+
+	return c.respond(resp.Data, resp.Code()), nil
+}
+
+func (c *simCard) respond(data []byte, code iso.Code) []byte {
+	if len(data) > iso.MaxLenRespDataStandard {
+		c.pending = data[iso.MaxLenRespDataStandard:]
+		data = data[:iso.MaxLenRespDataStandard]
+
+		n := len(c.pending)
+		if n > 0xff {
+			n = 0
+		}
+
+		code = iso.Code{0x61, byte(n)}
+	} else {
+		c.pending = nil
+	}
+
+	resp := &iso.RAPDU{Data: data, SW1: code[0], SW2: code[1]}
+	respBuf, _ := resp.Bytes()
+
+	return respBuf
+}
+
+func (c *simCard) BeginTransaction() error {
+	return nil
+}
+
+func (c *simCard) EndTransaction() error {
+	return nil
+}
+
+func (c *simCard) Close() error {
+	return nil
+}
+
+func (c *simCard) Base() iso.PCSCCard {
+	return c
+}
+
+// header returns the header of the command APDU including the
+// length field as it has been sent by the host.
+func header(cmd *iso.CAPDU, buf []byte) []byte {
+	h := []byte{byte(cmd.Cla), byte(cmd.Ins), cmd.P1, cmd.P2}
+	if len(buf) > 4 && buf[4] == 0x00 {
+		return append(h, buf[4:7]...)
+	}
+
+	return append(h, buf[4])
+}
+
+// application handles the unprotected test commands.
+func application(cmd *iso.CAPDU) *iso.RAPDU {
+	switch cmd.Ins {
+	case insEcho:
+		return &iso.RAPDU{Data: cmd.Data, SW1: iso.ErrSuccess[0], SW2: iso.ErrSuccess[1]}
+
+	case insGenerate:
+		n := int(cmd.P1)<<8 | int(cmd.P2)
+		return &iso.RAPDU{Data: generate(n), SW1: iso.ErrSuccess[0], SW2: iso.ErrSuccess[1]}
+
+	default:
+		return status(iso.ErrUnsupportedInstruction)
+	}
+}
+
+func generate(n int) []byte {
+	return bytes.Repeat([]byte{0xA5}, n)
+}
+
+func status(code iso.Code) *iso.RAPDU {
+	return &iso.RAPDU{SW1: code[0], SW2: code[1]}
+}
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
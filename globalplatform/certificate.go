@@ -0,0 +1,371 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+var (
+	ErrInvalidCertificate = errors.New("invalid certificate")
+	ErrInvalidSignature   = errors.New("invalid signature")
+)
+
+// Data objects of GlobalPlatform certificates
+// See: GlobalPlatform Card Specification Amendment F Section 6.3 Certificate Format
+const (
+	TagCertificateStore     tlv.Tag = 0xBF21
+	TagCertificate          tlv.Tag = 0x7F21
+	TagSerialNumber         tlv.Tag = 0x93
+	TagCAIdentifier         tlv.Tag = 0x42
+	TagSubjectIdentifier    tlv.Tag = 0x5F20
+	TagKeyUsage             tlv.Tag = 0x95
+	TagEffectiveDate        tlv.Tag = 0x5F25
+	TagExpirationDate       tlv.Tag = 0x5F24
+	TagPublicKey            tlv.Tag = 0x7F49
+	TagPublicKeyQ           tlv.Tag = 0xB0
+	TagKeyParameterRef      tlv.Tag = 0xF0
+	TagSignature            tlv.Tag = 0x5F37
+	TagControlReference     tlv.Tag = 0xA6
+	TagKeyIdentifierVersion tlv.Tag = 0x83
+)
+
+// Key parameter references of elliptic curves
+// See: GlobalPlatform Card Specification Amendment E Section 4.5 Key Parameter Reference Values
+const (
+	KeyParameterP256 byte = 0x00
+	KeyParameterP384 byte = 0x01
+	KeyParameterP521 byte = 0x02
+)
+
+// Certificate is a certificate of a security domain or off-card entity (OCE).
+// Certificates are either encoded in the compact GlobalPlatform format
+// (tag '7F21') or as X.509 certificates.
+type Certificate struct {
+	// Raw contains the complete encoded certificate.
+	Raw []byte
+
+	SerialNumber      []byte
+	CAIdentifier      []byte
+	SubjectIdentifier []byte
+	KeyUsage          []byte
+
+	PublicKey *ecdsa.PublicKey
+	Signature []byte
+
+	// X509 is the parsed certificate if it has been encoded as X.509 certificate.
+	X509 *x509.Certificate
+
+	signed []byte
+	hash   crypto.Hash
+}
+
+// ParseCertificate parses a single certificate encoded in the GlobalPlatform
+// or X.509 format.
+func ParseCertificate(b []byte) (*Certificate, error) {
+	certs, err := ParseCertificates(b)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certs) != 1 {
+		return nil, fmt.Errorf("%w: expected a single certificate, got %d", ErrInvalidCertificate, len(certs))
+	}
+
+	return certs[0], nil
+}
+
+// ParseCertificates parses a list of concatenated certificates as
+// they are returned by the certificate store of a security domain.
+func ParseCertificates(b []byte) (certs []*Certificate, err error) {
+	for len(b) > 0 {
+		var cert *Certificate
+
+		// X.509 certificates are DER-encoded ASN.1 sequences
+		if b[0] == 0x30 {
+			var seq asn1.RawValue
+
+			rest, err := asn1.Unmarshal(b, &seq)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+			}
+
+			if cert, err = parseX509Certificate(seq.FullBytes); err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, cert)
+			b = rest
+
+			continue
+		}
+
+		tag, value, rest, err := nextTLV(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+
+		raw := b[:len(b)-len(rest)]
+		b = rest
+
+		switch tag {
+		case TagCertificateStore:
+			more, err := ParseCertificates(value)
+			if err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, more...)
+
+		case TagCertificate:
+			if cert, err = parseGPCertificate(raw, value); err != nil {
+				return nil, err
+			}
+
+			certs = append(certs, cert)
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected tag %x", ErrInvalidCertificate, tag)
+		}
+	}
+
+	return certs, nil
+}
+
+func parseX509Certificate(raw []byte) (*Certificate, error) {
+	c, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	pub, ok := c.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: public key is not an ECDSA key", ErrInvalidCertificate)
+	}
+
+	return &Certificate{
+		Raw:               raw,
+		SerialNumber:      c.SerialNumber.Bytes(),
+		CAIdentifier:      c.AuthorityKeyId,
+		SubjectIdentifier: c.SubjectKeyId,
+		PublicKey:         pub,
+		Signature:         c.Signature,
+		X509:              c,
+	}, nil
+}
+
+func parseGPCertificate(raw, value []byte) (*Certificate, error) {
+	c := &Certificate{
+		Raw: raw,
+	}
+
+	var (
+		q      []byte
+		keyRef = KeyParameterP256
+	)
+
+	for buf := value; len(buf) > 0; {
+		tag, v, rest, err := nextTLV(buf)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+		}
+
+		switch tag {
+		case TagSerialNumber:
+			c.SerialNumber = v
+		case TagCAIdentifier:
+			c.CAIdentifier = v
+		case TagSubjectIdentifier:
+			c.SubjectIdentifier = v
+		case TagKeyUsage:
+			c.KeyUsage = v
+		case TagPublicKey:
+			for pbuf := v; len(pbuf) > 0; {
+				ptag, pv, prest, err := nextTLV(pbuf)
+				if err != nil {
+					return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+				}
+
+				switch {
+				case ptag == TagPublicKeyQ:
+					q = pv
+				case ptag == TagKeyParameterRef && len(pv) == 1:
+					keyRef = pv[0]
+				}
+
+				pbuf = prest
+			}
+		case TagSignature:
+			c.Signature = v
+			c.signed = value[:len(value)-len(buf)]
+		}
+
+		buf = rest
+	}
+
+	if q == nil {
+		return nil, fmt.Errorf("%w: missing public key", ErrInvalidCertificate)
+	}
+
+	var curve ecdh.Curve
+
+	switch keyRef {
+	case KeyParameterP256:
+		curve, c.hash = ecdh.P256(), crypto.SHA256
+	case KeyParameterP384:
+		curve, c.hash = ecdh.P384(), crypto.SHA384
+	case KeyParameterP521:
+		curve, c.hash = ecdh.P521(), crypto.SHA512
+	default:
+		return nil, fmt.Errorf("%w: unsupported key parameter reference %#02x", ErrInvalidCertificate, keyRef)
+	}
+
+	pub, err := curve.NewPublicKey(q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	if c.PublicKey, err = ecdsaPublicKey(pub); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCertificate, err)
+	}
+
+	return c, nil
+}
+
+// CheckSignature verifies that the certificate has been signed by the provided CA key.
+func (c *Certificate) CheckSignature(ca *ecdsa.PublicKey) error {
+	if c.X509 != nil {
+		parent := &x509.Certificate{
+			PublicKey:          ca,
+			PublicKeyAlgorithm: x509.ECDSA,
+		}
+
+		if err := parent.CheckSignature(c.X509.SignatureAlgorithm, c.X509.RawTBSCertificate, c.X509.Signature); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSignature, err)
+		}
+
+		return nil
+	}
+
+	if c.signed == nil || len(c.Signature)%2 != 0 {
+		return ErrInvalidSignature
+	}
+
+	h := c.hash.New()
+	h.Write(c.signed)
+
+	n := len(c.Signature) / 2
+	r := new(big.Int).SetBytes(c.Signature[:n])
+	s := new(big.Int).SetBytes(c.Signature[n:])
+
+	if !ecdsa.Verify(ca, h.Sum(nil), r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// GetCertificates retrieves the certificate chain of a security domain key
+// from the certificate store. The last certificate contains the public key
+// of the referenced key.
+// See: GlobalPlatform Card Specification Amendment F Section 7.4.3 GET DATA (Certificate Store)
+func GetCertificates(card *iso.Card, kid, kvn byte) ([]*Certificate, error) {
+	return GetCertificatesContext(context.Background(), card, kid, kvn)
+}
+
+// GetCertificatesContext is like GetCertificates but allows to pass a context.
+func GetCertificatesContext(ctx context.Context, card *iso.Card, kid, kvn byte) ([]*Certificate, error) {
+	data, err := tlv.EncodeBER(
+		tlv.New(TagControlReference,
+			tlv.New(TagKeyIdentifierVersion, kid, kvn),
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := card.SendContext(ctx, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  iso.InsGetData,
+		P1:   byte(TagCertificateStore >> 8),
+		P2:   byte(TagCertificateStore & 0xFF),
+		Data: data,
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get certificates: %w", err)
+	}
+
+	return ParseCertificates(resp)
+}
+
+// ecdsaPublicKey converts an ECDH public key into an ECDSA public key.
+func ecdsaPublicKey(pub *ecdh.PublicKey) (*ecdsa.PublicKey, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaPub, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected key type %T", ErrInvalidKey, key)
+	}
+
+	return ecdsaPub, nil
+}
+
+// nextTLV decodes the next BER-TLV data object without decoding
+// the values of constructed data objects.
+// This is required as the public key tag 'B0' of GlobalPlatform
+// certificates is a constructed tag with a primitive value.
+func nextTLV(buf []byte) (tag tlv.Tag, value, rest []byte, err error) {
+	if buf, err = tag.UnmarshalBER(buf); err != nil {
+		return 0, nil, nil, err
+	}
+
+	if len(buf) < 1 {
+		return 0, nil, nil, errInvalidLength
+	}
+
+	l := int(buf[0])
+	buf = buf[1:]
+
+	if l > 0x80 {
+		n := l & 0x7F
+		if n > 3 || len(buf) < n {
+			return 0, nil, nil, errInvalidLength
+		}
+
+		l = 0
+		for _, b := range buf[:n] {
+			l = l<<8 | int(b)
+		}
+
+		buf = buf[n:]
+	} else if l == 0x80 {
+		return 0, nil, nil, errInvalidLength
+	}
+
+	if len(buf) < l {
+		return 0, nil, nil, errInvalidLength
+	}
+
+	return tag, buf[:l], buf[l:], nil
+}
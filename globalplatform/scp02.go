@@ -0,0 +1,300 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	"crypto/subtle"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/internal/mac"
+	"cunicu.li/go-iso7816/sm"
+)
+
+// Options of the i parameter of SCP02
+// See: GlobalPlatform Card Specification Section E.1.1 SCP02 Secure Channel Protocol Identifier
+const (
+	SCP02OptionThreeKeys     byte = 0x01 // Three secure channel keys
+	SCP02OptionExplicit      byte = 0x04 // Explicit initiation mode
+	SCP02OptionICVEncryption byte = 0x10 // ICV encryption for C-MAC session
+	SCP02OptionPseudoRandom  byte = 0x40 // Well-known pseudo-random algorithm for card challenges
+
+	// SCP02Option15 is the i=15 option of SCP02.
+	SCP02Option15 = SCP02OptionThreeKeys | SCP02OptionExplicit | SCP02OptionICVEncryption
+
+	// SCP02Option55 is the i=55 option of SCP02 which adds pseudo-random card challenges.
+	SCP02Option55 = SCP02Option15 | SCP02OptionPseudoRandom
+)
+
+// Derivation constants of the SCP02 session keys
+// See: GlobalPlatform Card Specification Section E.4.1 DES Session Keys
+//
+//nolint:gochecknoglobals
+var (
+	derivationCMAC = [2]byte{0x01, 0x01}
+	derivationENC  = [2]byte{0x01, 0x82}
+)
+
+const lenSCP02CardChallenge = 6
+
+// SCP02Config contains the parameters for opening a SCP02 secure channel.
+type SCP02Config struct {
+	// Keys is the static key set of the security domain.
+	Keys StaticKeys
+
+	// SecurityLevel is the protection applied to all commands after
+	// the secure channel has been opened.
+	// SCP02 sessions only support C-MAC and C-DECRYPTION.
+	SecurityLevel SecurityLevel
+
+	// Option is the i parameter of the implemented SCP02 variant.
+	// If zero, SCP02Option15 is used.
+	Option byte
+
+	// AID is the application identifier of the security domain which
+	// is used to verify pseudo-random card challenges.
+	// If nil, iso7816.AidCardManager is assumed.
+	AID []byte
+}
+
+// OpenSCP02 opens a SCP02 secure channel session with the currently selected security domain.
+// The returned card protects all exchanged APDUs according to the requested security level.
+// See: GlobalPlatform Card Specification Appendix E
+func OpenSCP02(card *iso.Card, cfg SCP02Config) (*sm.Card, error) {
+	return OpenSCP02Context(context.Background(), card, cfg)
+}
+
+// OpenSCP02Context is like OpenSCP02 but allows to pass a context.
+func OpenSCP02Context(ctx context.Context, card *iso.Card, cfg SCP02Config) (*sm.Card, error) {
+	if cfg.SecurityLevel.Has(SecurityLevelRMAC) {
+		return nil, fmt.Errorf("%w: R-MAC is not supported for SCP02", ErrInvalidSecurityLevel)
+	} else if err := cfg.SecurityLevel.Validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Option == 0 {
+		cfg.Option = SCP02Option15
+	}
+
+	if cfg.Option&(SCP02OptionThreeKeys|SCP02OptionExplicit) != SCP02OptionThreeKeys|SCP02OptionExplicit {
+		return nil, fmt.Errorf("%w: SCP02 option i=%02x", ErrUnsupportedProtocol, cfg.Option)
+	}
+
+	hostChallenge, err := newHostChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := card.SendContext(ctx, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  InsInitializeUpdate,
+		P1:   cfg.Keys.Version,
+		P2:   0x00,
+		Data: hostChallenge,
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize update: %w", err)
+	}
+
+	// Key diversification data, key information, sequence counter, card challenge and card cryptogram
+	if len(resp) != lenKeyDivData+2+2+lenSCP02CardChallenge+lenCryptogram {
+		return nil, fmt.Errorf("%w: INITIALIZE UPDATE response has invalid length", ErrInvalidResponse)
+	}
+
+	if scp := resp[lenKeyDivData+1]; scp != 0x02 {
+		return nil, fmt.Errorf("%w: %#02x", ErrUnsupportedProtocol, scp)
+	}
+
+	rest := resp[lenKeyDivData+2:]
+	seq := rest[:2]
+	cardChallenge := rest[2 : 2+lenSCP02CardChallenge]
+	cardCryptogram := rest[2+lenSCP02CardChallenge:]
+
+	sess, err := newSCP02Session(cfg.Keys, seq, cfg.Option)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Option&SCP02OptionPseudoRandom != 0 {
+		if err := verifySCP02CardChallenge(cfg, sess, cardChallenge); err != nil {
+			return nil, err
+		}
+	}
+
+	expCardCryptogram, err := scp02Cryptogram(sess.sENC, hostChallenge, seq, cardChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(cardCryptogram, expCardCryptogram) != 1 {
+		return nil, ErrInvalidCryptogram
+	}
+
+	hostCryptogram, err := scp02Cryptogram(sess.sENC, seq, cardChallenge, hostChallenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return externalAuthenticate(ctx, card, sess, cfg.SecurityLevel, hostCryptogram)
+}
+
+// verifySCP02CardChallenge checks a pseudo-random card challenge which consists of
+// the leftmost bytes of a C-MAC over the AID of the security domain using the S-MAC session key.
+// See: GlobalPlatform Card Specification Section E.4.2 Authentication Cryptograms
+func verifySCP02CardChallenge(cfg SCP02Config, sess *scp02Session, cardChallenge []byte) error {
+	aid := cfg.AID
+	if aid == nil {
+		aid = iso.AidCardManager
+	}
+
+	expCardChallenge, err := mac.Retail(sess.sMAC, nil, mac.Pad(aid, des.BlockSize))
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(cardChallenge, expCardChallenge[:lenSCP02CardChallenge]) != 1 {
+		return ErrInvalidCardChallenge
+	}
+
+	return nil
+}
+
+// scp02Cryptogram computes the card or host authentication cryptogram as a
+// full triple DES MAC over the concatenated challenges and sequence counter.
+// See: GlobalPlatform Card Specification Section E.4.2 Authentication Cryptograms
+func scp02Cryptogram(key []byte, parts ...[]byte) ([]byte, error) {
+	var msg []byte
+	for _, part := range parts {
+		msg = append(msg, part...)
+	}
+
+	enc, err := sm.NewTripleDES(key)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := mac.Pad(msg, des.BlockSize)
+	cipher.NewCBCEncrypter(enc, make([]byte, des.BlockSize)).CryptBlocks(padded, padded)
+
+	return padded[len(padded)-des.BlockSize:], nil
+}
+
+// scp02SessionKey derives a session key from a static key and the sequence counter.
+// See: GlobalPlatform Card Specification Section E.4.1 DES Session Keys
+func scp02SessionKey(key []byte, constant [2]byte, seq []byte) ([]byte, error) {
+	enc, err := sm.NewTripleDES(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sk := make([]byte, 16)
+	copy(sk, constant[:])
+	copy(sk[2:], seq)
+
+	cipher.NewCBCEncrypter(enc, make([]byte, des.BlockSize)).CryptBlocks(sk, sk)
+
+	return sk, nil
+}
+
+var _ levelWrapper = (*scp02Session)(nil)
+
+// scp02Session implements the secure messaging of SCP02.
+// See: GlobalPlatform Card Specification Section E.4.4 APDU Command C-MAC Generation and Verification
+type scp02Session struct {
+	level  SecurityLevel
+	option byte
+
+	sENC, sMAC []byte
+
+	enc    cipher.Block
+	icvKey cipher.Block
+
+	icv []byte
+}
+
+func newSCP02Session(keys StaticKeys, seq []byte, option byte) (s *scp02Session, err error) {
+	s = &scp02Session{
+		level:  SecurityLevelCMAC,
+		option: option,
+	}
+
+	if s.sENC, err = scp02SessionKey(keys.Enc, derivationENC, seq); err != nil {
+		return nil, err
+	}
+
+	if s.sMAC, err = scp02SessionKey(keys.MAC, derivationCMAC, seq); err != nil {
+		return nil, err
+	}
+
+	if s.enc, err = sm.NewTripleDES(s.sENC); err != nil {
+		return nil, err
+	}
+
+	if s.icvKey, err = des.NewCipher(s.sMAC[:8]); err != nil { //nolint:gosec
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return s, nil
+}
+
+func (s *scp02Session) setSecurityLevel(level SecurityLevel) {
+	s.level = level
+}
+
+// Wrap protects a command APDU by a C-MAC computed over the modified APDU
+// and optionally encrypts the command data.
+func (s *scp02Session) Wrap(cmd *iso.CAPDU) (*iso.CAPDU, error) {
+	if !s.level.Has(SecurityLevelCMAC) {
+		return cmd, nil
+	}
+
+	cla, err := withSecureMessaging(cmd.Cla)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ICV of the first command is zero, all following commands
+	// use the (encrypted) C-MAC of the previous command.
+	icv := make([]byte, des.BlockSize)
+	if s.icv != nil {
+		copy(icv, s.icv)
+
+		if s.option&SCP02OptionICVEncryption != 0 {
+			s.icvKey.Encrypt(icv, icv)
+		}
+	}
+
+	macInput := []byte{byte(cla), byte(cmd.Ins), cmd.P1, cmd.P2}
+	macInput = appendLc(macInput, len(cmd.Data)+lenMAC, cmd.Ne)
+	macInput = append(macInput, cmd.Data...)
+
+	if s.icv, err = mac.Retail(s.sMAC, icv, mac.Pad(macInput, des.BlockSize)); err != nil {
+		return nil, err
+	}
+
+	data := cmd.Data
+	if s.level.Has(SecurityLevelCDEC) && len(data) > 0 {
+		padded := mac.Pad(data, des.BlockSize)
+		data = make([]byte, len(padded))
+		cipher.NewCBCEncrypter(s.enc, make([]byte, des.BlockSize)).CryptBlocks(data, padded)
+	}
+
+	return &iso.CAPDU{
+		Cla:  cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: append(data, s.icv...),
+		Ne:   cmd.Ne,
+	}, nil
+}
+
+// Unwrap returns the response unmodified as SCP02 sessions do not protect responses.
+func (s *scp02Session) Unwrap(resp *iso.RAPDU) (*iso.RAPDU, error) {
+	return resp, nil
+}
@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform_test
+
+import (
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	gp "cunicu.li/go-iso7816/globalplatform"
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+// scp02SD is a card-side implementation of SCP02.
+type scp02SD struct {
+	keys   gp.StaticKeys
+	option byte
+	seq    int
+
+	authenticated  bool
+	level          gp.SecurityLevel
+	hostCryptogram []byte
+
+	sENC, sMAC []byte
+	icv        []byte
+}
+
+func tripleDES(key []byte) cipher.Block {
+	b, err := des.NewTripleDESCipher(append(append([]byte{}, key...), key[:8]...)) //nolint:gosec
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func cbc(key, data []byte) []byte {
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(tripleDES(key), make([]byte, 8)).CryptBlocks(out, data)
+
+	return out
+}
+
+func (sd *scp02SD) process(cmd *iso.CAPDU, _ []byte) *iso.RAPDU {
+	if cmd.Ins == gp.InsInitializeUpdate {
+		return sd.initializeUpdate(cmd)
+	}
+
+	if cmd.Cla.SecureMessaging() == iso.SecureMessagingNone {
+		if sd.authenticated && sd.level != gp.SecurityLevelNone {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		return application(cmd)
+	}
+
+	if len(cmd.Data) < 8 || sd.sMAC == nil {
+		return status(iso.ErrIncorrectSecureMessagingDataObjects)
+	}
+
+	data, cmac := cmd.Data[:len(cmd.Data)-8], cmd.Data[len(cmd.Data)-8:]
+
+	if sd.authenticated && sd.level.Has(gp.SecurityLevelCDEC) && len(data) > 0 {
+		padded := make([]byte, len(data))
+		cipher.NewCBCDecrypter(tripleDES(sd.sENC), make([]byte, 8)).CryptBlocks(padded, data)
+
+		var err error
+		if data, err = mac.Unpad(padded); err != nil {
+			return status(iso.ErrIncorrectSecureMessagingDataObjects)
+		}
+	}
+
+	icv := make([]byte, 8)
+	if sd.icv != nil {
+		b, _ := des.NewCipher(sd.sMAC[:8]) //nolint:gosec
+		b.Encrypt(icv, sd.icv)
+	}
+
+	// C-MAC is computed over the modified APDU with plain data
+	macInput := []byte{byte(cmd.Cla), byte(cmd.Ins), cmd.P1, cmd.P2, byte(len(data) + 8)}
+	macInput = append(macInput, data...)
+
+	expCMAC, err := mac.Retail(sd.sMAC, icv, mac.Pad(macInput, 8))
+	if err != nil {
+		panic(err)
+	}
+
+	if subtle.ConstantTimeCompare(cmac, expCMAC) != 1 {
+		sd.authenticated = false
+		return status(iso.ErrSecurityStatusNotSatisfied)
+	}
+
+	sd.icv = expCMAC
+
+	if !sd.authenticated {
+		if cmd.Ins != gp.InsExternalAuthenticate || subtle.ConstantTimeCompare(data, sd.hostCryptogram) != 1 {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		sd.authenticated = true
+		sd.level = gp.SecurityLevel(cmd.P1)
+
+		return status(iso.ErrSuccess)
+	}
+
+	return application(&iso.CAPDU{
+		Cla:  cmd.Cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: data,
+	})
+}
+
+func (sd *scp02SD) initializeUpdate(cmd *iso.CAPDU) *iso.RAPDU {
+	if cmd.P1 != 0 && cmd.P1 != sd.keys.Version {
+		return status(iso.ErrReferenceNotFound)
+	}
+
+	sd.seq++
+	seq := []byte{byte(sd.seq >> 8), byte(sd.seq)}
+
+	sd.sENC = cbc(sd.keys.Enc, append([]byte{0x01, 0x82}, append(seq, make([]byte, 12)...)...))
+	sd.sMAC = cbc(sd.keys.MAC, append([]byte{0x01, 0x01}, append(seq, make([]byte, 12)...)...))
+
+	cardChallenge := make([]byte, 6)
+	if sd.option&gp.SCP02OptionPseudoRandom != 0 {
+		// Derived from the AID of the security domain
+		icv, _ := mac.Retail(sd.sMAC, nil, mac.Pad(iso.AidCardManager, 8))
+		copy(cardChallenge, icv)
+	} else if _, err := rand.Read(cardChallenge); err != nil {
+		panic(err)
+	}
+
+	cryptogram := func(parts ...[]byte) []byte {
+		var msg []byte
+		for _, p := range parts {
+			msg = append(msg, p...)
+		}
+
+		ct := cbc(sd.sENC, mac.Pad(msg, 8))
+
+		return ct[len(ct)-8:]
+	}
+
+	sd.hostCryptogram = cryptogram(seq, cardChallenge, cmd.Data)
+	sd.icv = nil
+	sd.authenticated = false
+	sd.level = gp.SecurityLevelNone
+
+	data := make([]byte, 10) // Key diversification data
+	data = append(data, sd.keys.Version, 0x02)
+	data = append(data, seq...)
+	data = append(data, cardChallenge...)
+	data = append(data, cryptogram(cmd.Data, seq, cardChallenge)...)
+
+	return &iso.RAPDU{Data: data, SW1: 0x90, SW2: 0x00}
+}
+
+func newSCP02Card(option byte) *iso.Card {
+	return iso.NewCard(&simCard{
+		sd: &scp02SD{
+			keys: gp.StaticKeys{
+				Version: 0x20,
+				Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+				MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+			},
+			option: option,
+		},
+	})
+}
+
+func TestSCP02(t *testing.T) {
+	levels := []gp.SecurityLevel{
+		gp.SecurityLevelNone,
+		gp.SecurityLevelCMAC,
+		gp.SecurityLevelCMAC | gp.SecurityLevelCDEC,
+	}
+
+	for _, level := range levels {
+		for _, option := range []byte{gp.SCP02Option15, gp.SCP02Option55} {
+			t.Run(fmt.Sprintf("level=%02x/i=%02x", byte(level), option), func(t *testing.T) {
+				require := require.New(t)
+
+				scp, err := gp.OpenSCP02(newSCP02Card(option), gp.SCP02Config{
+					Keys: gp.StaticKeys{
+						Version: 0x20,
+						Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+						MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+					},
+					SecurityLevel: level,
+					Option:        option,
+				})
+				require.NoError(err)
+
+				card := iso.NewCard(scp)
+
+				for _, n := range []int{0, 1, 8, 200} {
+					resp, err := card.Send(&iso.CAPDU{
+						Cla:  iso.ClassProprietary,
+						Ins:  insEcho,
+						Data: generate(n),
+						Ne:   iso.MaxLenRespDataStandard,
+					})
+					require.NoError(err)
+
+					if n == 0 {
+						require.Empty(resp)
+					} else {
+						require.Equal(generate(n), resp)
+					}
+				}
+
+				resp, err := card.Send(&iso.CAPDU{
+					Cla: iso.ClassProprietary,
+					Ins: insGenerate,
+					P1:  0x02,
+					P2:  0x58,
+					Ne:  iso.MaxLenRespDataStandard,
+				})
+				require.NoError(err)
+				require.Equal(generate(600), resp)
+			})
+		}
+	}
+}
+
+func TestSCP02InvalidKeys(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP02(newSCP02Card(gp.SCP02Option15), gp.SCP02Config{
+		Keys: gp.StaticKeys{
+			Enc: unhex("0123456789abcdeffedcba9876543210"),
+			MAC: unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: gp.SecurityLevelCMAC,
+	})
+	require.ErrorIs(err, gp.ErrInvalidCryptogram)
+
+	// The card rejects the host cryptogram if the MAC key does not match
+	_, err = gp.OpenSCP02(newSCP02Card(gp.SCP02Option15), gp.SCP02Config{
+		Keys:          gp.DefaultKeys(),
+		SecurityLevel: gp.SecurityLevelCMAC,
+	})
+	require.ErrorIs(err, iso.ErrSecurityStatusNotSatisfied)
+}
+
+func TestSCP02InvalidCardChallenge(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP02(newSCP02Card(gp.SCP02Option55), gp.SCP02Config{
+		Keys: gp.StaticKeys{
+			Version: 0x20,
+			Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+			MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: gp.SecurityLevelCMAC,
+		Option:        gp.SCP02Option55,
+		AID:           iso.AidOpenPGP,
+	})
+	require.ErrorIs(err, gp.ErrInvalidCardChallenge)
+}
+
+func TestSCP02InvalidSecurityLevel(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP02(newSCP02Card(gp.SCP02Option15), gp.SCP02Config{
+		Keys:          gp.DefaultKeys(),
+		SecurityLevel: gp.SecurityLevelCMAC | gp.SecurityLevelRMAC,
+	})
+	require.ErrorIs(err, gp.ErrInvalidSecurityLevel)
+}
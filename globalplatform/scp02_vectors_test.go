@@ -0,0 +1,151 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"crypto/cipher"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/internal/mac"
+	"cunicu.li/go-iso7816/sm"
+	"cunicu.li/go-iso7816/test"
+)
+
+// tdesCBC encrypts a hex encoded message with 3DES in CBC mode using a zero IV.
+func tdesCBC(key []byte, msg string) []byte {
+	b, err := sm.NewTripleDES(key)
+	if err != nil {
+		panic(err)
+	}
+
+	out := unhex(msg)
+	cipher.NewCBCEncrypter(b, make([]byte, b.BlockSize())).CryptBlocks(out, out)
+
+	return out
+}
+
+// TestSCP02Vectors checks the session keys, the authentication cryptograms and
+// the C-MAC chaining and C-DECRYPTION using fixed challenges.
+// The expected values have been computed independently with the OpenSSL command line tools.
+// In addition, the inputs of the derivations are spelled out according to
+// GlobalPlatform Card Specification Appendix E to check their layout.
+func TestSCP02Vectors(t *testing.T) {
+	require := require.New(t)
+
+	keys := StaticKeys{
+		Enc: unhex("404142434445464748494a4b4c4d4e4f"),
+		MAC: unhex("505152535455565758595a5b5c5d5e5f"),
+	}
+
+	hostChallenge := unhex("b0b1b2b3b4b5b6b7")
+	cardChallenge := unhex("6e7599f0af83")
+	seq := unhex("002a")
+
+	sess, err := newSCP02Session(keys, seq, SCP02Option55)
+	require.NoError(err)
+	require.Equal(unhex("7aa8de1a36f4f51afbc7e1579f778b44"), sess.sENC)
+	require.Equal(unhex("2164147fb45894f3824f2bbf7b21aef5"), sess.sMAC)
+
+	// Derivation constant || sequence counter || zero padding
+	require.Equal(sess.sENC, tdesCBC(keys.Enc, "0182"+"002a"+"000000000000000000000000"))
+	require.Equal(sess.sMAC, tdesCBC(keys.MAC, "0101"+"002a"+"000000000000000000000000"))
+
+	// Pseudo-random card challenge
+	err = verifySCP02CardChallenge(SCP02Config{}, sess, cardChallenge)
+	require.NoError(err)
+
+	// Retail MAC over the padded AID of the security domain
+	expCardChallenge, err := mac.Retail(sess.sMAC, nil, unhex("a000000151000000"+"8000000000000000"))
+	require.NoError(err)
+	require.Equal(cardChallenge, expCardChallenge[:6])
+
+	cardCryptogram, err := scp02Cryptogram(sess.sENC, hostChallenge, seq, cardChallenge)
+	require.NoError(err)
+	require.Equal(unhex("e65650eb103d5005"), cardCryptogram)
+
+	// Host challenge || sequence counter || card challenge || padding
+	require.Equal(cardCryptogram, tdesCBC(sess.sENC, "b0b1b2b3b4b5b6b7"+"002a"+"6e7599f0af83"+"8000000000000000")[16:])
+
+	hostCryptogram, err := scp02Cryptogram(sess.sENC, seq, cardChallenge, hostChallenge)
+	require.NoError(err)
+	require.Equal(unhex("ca01a8b27b0abcc7"), hostCryptogram)
+
+	// Sequence counter || card challenge || host challenge || padding
+	require.Equal(hostCryptogram, tdesCBC(sess.sENC, "002a"+"6e7599f0af83"+"b0b1b2b3b4b5b6b7"+"8000000000000000")[16:])
+
+	level := SecurityLevelCMAC | SecurityLevelCDEC
+
+	// The ICV of the first C-MAC is zero
+	require.Equal(unhex("8482030010ca01a8b27b0abcc72372920793de909a"), wrap(t, sess, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  InsExternalAuthenticate,
+		P1:   byte(level),
+		Data: hostCryptogram,
+	}))
+
+	sess.setSecurityLevel(level)
+
+	// C-MAC over the plain data with the encrypted C-MAC of the previous command as ICV
+	require.Equal(unhex("84e280001898bcec97b7a812f0145ee6ab24e3ebb73bd400dcbbbcb26b00"), wrap(t, sess, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  0xE2,
+		P1:   0x80,
+		Data: unhex("0102030405060708090a"),
+		Ne:   iso.MaxLenRespDataStandard,
+	}))
+
+	require.Equal(unhex("84ca00ff0868d8ddb736b3a51d00"), wrap(t, sess, &iso.CAPDU{
+		Cla: iso.ClassProprietary,
+		Ins: iso.InsGetData,
+		P2:  0xFF,
+		Ne:  iso.MaxLenRespDataStandard,
+	}))
+}
+
+// TestSCP02Transcript opens a session with the fixed challenges of TestSCP02Vectors
+// to check the encoding of the exchanged APDUs including the order of the challenges.
+// The option i=55 does not include R-MAC. Hence, responses are passed unmodified.
+func TestSCP02Transcript(t *testing.T) {
+	require := require.New(t)
+
+	withHostChallenge(t, unhex("b0b1b2b3b4b5b6b7"))
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	smCard, err := OpenSCP02(iso.NewCard(mockCard), SCP02Config{
+		Keys: StaticKeys{
+			Enc: unhex("404142434445464748494a4b4c4d4e4f"),
+			MAC: unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: SecurityLevelCMAC | SecurityLevelCDEC,
+		Option:        SCP02Option55,
+	})
+	require.NoError(err)
+
+	card := iso.NewCard(smCard)
+
+	resp, err := card.Send(&iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  0xE2,
+		P1:   0x80,
+		Data: unhex("0102030405060708090a"),
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	require.NoError(err)
+	require.Equal(unhex("cafe"), resp)
+
+	_, err = card.Send(&iso.CAPDU{
+		Cla: iso.ClassProprietary,
+		Ins: iso.InsGetData,
+		P2:  0xFF,
+		Ne:  iso.MaxLenRespDataStandard,
+	})
+	require.ErrorIs(err, iso.ErrReferenceNotFound)
+
+	require.NoError(mockCard.Close())
+}
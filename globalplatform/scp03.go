@@ -0,0 +1,418 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/internal/mac"
+	"cunicu.li/go-iso7816/sm"
+)
+
+// Options of the i parameter of SCP03
+// See: GlobalPlatform Card Specification Amendment D Section 5.1 Secure Channel Protocol Identifier
+const (
+	scp03OptionS16          byte = 0x01 // S16 mode
+	scp03OptionPseudoRandom byte = 0x10 // Pseudo-random card challenge
+	scp03OptionRMAC         byte = 0x20 // R-MAC support
+	scp03OptionRENC         byte = 0x40 // R-ENCRYPTION support
+)
+
+// Derivation constants of the SCP03 key derivation function
+// See: GlobalPlatform Card Specification Amendment D Section 6.2.1 AES-based Key Derivation
+const (
+	derivationCardCryptogram byte = 0x00
+	derivationHostCryptogram byte = 0x01
+	derivationCardChallenge  byte = 0x02
+	derivationSENC           byte = 0x04
+	derivationSMAC           byte = 0x06
+	derivationSRMAC          byte = 0x07
+)
+
+const (
+	lenChallenge      = 8
+	lenCryptogram     = 8
+	lenMAC            = 8
+	lenKeyDivData     = 10
+	lenKeyInfo        = 3
+	lenSequenceNumber = 3
+)
+
+// SCP03Config contains the parameters for opening a SCP03 secure channel.
+type SCP03Config struct {
+	// Keys is the static key set of the security domain.
+	Keys StaticKeys
+
+	// SecurityLevel is the protection applied to all commands and
+	// responses after the secure channel has been opened.
+	SecurityLevel SecurityLevel
+
+	// AID is the application identifier of the security domain which
+	// is used to verify pseudo-random card challenges.
+	// If nil, iso7816.AidCardManager is assumed.
+	AID []byte
+}
+
+// OpenSCP03 opens a SCP03 secure channel session with the currently selected security domain.
+// The returned card protects all exchanged APDUs according to the requested security level.
+// Cards created by iso7816.NewCard() for the returned card use the logical channel,
+// transfer limits and lock of the provided card.
+// See: GlobalPlatform Card Specification Amendment D
+func OpenSCP03(card *iso.Card, cfg SCP03Config) (*sm.Card, error) {
+	return OpenSCP03Context(context.Background(), card, cfg)
+}
+
+// OpenSCP03Context is like OpenSCP03 but allows to pass a context.
+func OpenSCP03Context(ctx context.Context, card *iso.Card, cfg SCP03Config) (*sm.Card, error) {
+	if err := cfg.SecurityLevel.Validate(); err != nil {
+		return nil, err
+	}
+
+	hostChallenge, err := newHostChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := card.SendContext(ctx, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  InsInitializeUpdate,
+		P1:   cfg.Keys.Version,
+		P2:   0x00,
+		Data: hostChallenge,
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize update: %w", err)
+	}
+
+	if len(resp) < lenKeyDivData+lenKeyInfo+lenChallenge+lenCryptogram {
+		return nil, fmt.Errorf("%w: INITIALIZE UPDATE response too short", ErrInvalidResponse)
+	}
+
+	keyInfo := resp[lenKeyDivData : lenKeyDivData+lenKeyInfo]
+	if keyInfo[1] != 0x03 {
+		return nil, fmt.Errorf("%w: %#02x", ErrUnsupportedProtocol, keyInfo[1])
+	}
+
+	i := keyInfo[2]
+	if i&scp03OptionS16 != 0 {
+		return nil, fmt.Errorf("%w: S16 mode", ErrUnsupportedProtocol)
+	}
+
+	if cfg.SecurityLevel.Has(SecurityLevelRMAC) && i&scp03OptionRMAC == 0 ||
+		cfg.SecurityLevel.Has(SecurityLevelRENC) && i&scp03OptionRENC == 0 {
+		return nil, fmt.Errorf("%w: not supported by card", ErrInvalidSecurityLevel)
+	}
+
+	rest := resp[lenKeyDivData+lenKeyInfo:]
+	cardChallenge := rest[:lenChallenge]
+	cardCryptogram := rest[lenChallenge : lenChallenge+lenCryptogram]
+
+	if i&scp03OptionPseudoRandom != 0 {
+		if err := verifySCP03CardChallenge(cfg, cardChallenge, rest[lenChallenge+lenCryptogram:]); err != nil {
+			return nil, err
+		}
+	}
+
+	derivationContext := append(append([]byte{}, hostChallenge...), cardChallenge...)
+
+	sess, err := newSCP03SessionFromStaticKeys(cfg.Keys, derivationContext)
+	if err != nil {
+		return nil, err
+	}
+
+	expCardCryptogram, err := scp03KDF(sess.sMAC, derivationCardCryptogram, derivationContext, 8*lenCryptogram)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(cardCryptogram, expCardCryptogram) != 1 {
+		return nil, ErrInvalidCryptogram
+	}
+
+	hostCryptogram, err := scp03KDF(sess.sMAC, derivationHostCryptogram, derivationContext, 8*lenCryptogram)
+	if err != nil {
+		return nil, err
+	}
+
+	return externalAuthenticate(ctx, card, sess, cfg.SecurityLevel, hostCryptogram)
+}
+
+// externalAuthenticate sends the EXTERNAL AUTHENTICATE command protected by a C-MAC
+// and raises the security level of the session afterwards.
+func externalAuthenticate(ctx context.Context, card *iso.Card, sess levelWrapper, level SecurityLevel, hostCryptogram []byte) (*sm.Card, error) {
+	smCard := sm.NewCard(card, sess)
+
+	if _, err := iso.NewCard(smCard).SendContext(ctx, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  InsExternalAuthenticate,
+		P1:   byte(level),
+		P2:   0x00,
+		Data: hostCryptogram,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to external authenticate: %w", err)
+	}
+
+	sess.setSecurityLevel(level)
+
+	return smCard, nil
+}
+
+func verifySCP03CardChallenge(cfg SCP03Config, cardChallenge, seq []byte) error {
+	if len(seq) < lenSequenceNumber {
+		return fmt.Errorf("%w: missing sequence counter", ErrInvalidResponse)
+	}
+
+	aid := cfg.AID
+	if aid == nil {
+		aid = iso.AidCardManager
+	}
+
+	expCardChallenge, err := scp03KDF(cfg.Keys.Enc, derivationCardChallenge, append(append([]byte{}, seq[:lenSequenceNumber]...), aid...), 8*lenChallenge)
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare(cardChallenge, expCardChallenge) != 1 {
+		return ErrInvalidCardChallenge
+	}
+
+	return nil
+}
+
+// scp03KDF implements the key derivation function in counter mode with AES-CMAC as PRF.
+// See: GlobalPlatform Card Specification Amendment D Section 6.2.1 AES-based Key Derivation
+// See: NIST SP 800-108 Section 5.1 KDF in Counter Mode
+func scp03KDF(key []byte, constant byte, context []byte, bits int) ([]byte, error) {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	out := make([]byte, 0, bits/8+aes.BlockSize)
+
+	for i := byte(1); len(out) < bits/8; i++ {
+		input := make([]byte, 11, 16+len(context))
+		input = append(input, constant, 0x00, byte(bits>>8), byte(bits), i)
+		input = append(input, context...)
+
+		out = append(out, mac.CMAC(b, input)...)
+	}
+
+	return out[:bits/8], nil
+}
+
+// levelWrapper is a secure messaging wrapper whose security level
+// is raised after the secure channel has been authenticated.
+type levelWrapper interface {
+	sm.Wrapper
+
+	setSecurityLevel(level SecurityLevel)
+}
+
+var _ levelWrapper = (*scp03Session)(nil)
+
+// scp03Session implements the secure messaging of SCP03 which is also used by SCP11.
+// See: GlobalPlatform Card Specification Amendment D Section 6.2 Cryptographic Usage
+type scp03Session struct {
+	level SecurityLevel
+
+	sENC, sMAC, sRMAC []byte
+
+	enc, mac, rmac cipher.Block
+
+	macChain []byte
+	counter  uint64
+}
+
+func newSCP03SessionFromStaticKeys(keys StaticKeys, derivationContext []byte) (*scp03Session, error) {
+	sENC, err := scp03KDF(keys.Enc, derivationSENC, derivationContext, 8*len(keys.Enc))
+	if err != nil {
+		return nil, err
+	}
+
+	sMAC, err := scp03KDF(keys.MAC, derivationSMAC, derivationContext, 8*len(keys.MAC))
+	if err != nil {
+		return nil, err
+	}
+
+	sRMAC, err := scp03KDF(keys.MAC, derivationSRMAC, derivationContext, 8*len(keys.MAC))
+	if err != nil {
+		return nil, err
+	}
+
+	return newSCP03Session(sENC, sMAC, sRMAC, nil)
+}
+
+func newSCP03Session(sENC, sMAC, sRMAC, macChain []byte) (s *scp03Session, err error) {
+	s = &scp03Session{
+		level:    SecurityLevelCMAC,
+		sENC:     sENC,
+		sMAC:     sMAC,
+		sRMAC:    sRMAC,
+		macChain: macChain,
+	}
+
+	if s.macChain == nil {
+		s.macChain = make([]byte, aes.BlockSize)
+	}
+
+	if s.enc, err = aes.NewCipher(sENC); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	if s.mac, err = aes.NewCipher(sMAC); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	if s.rmac, err = aes.NewCipher(sRMAC); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return s, nil
+}
+
+func (s *scp03Session) setSecurityLevel(level SecurityLevel) {
+	s.level = level
+}
+
+// icv returns the initial chaining vector for the encryption of
+// command data or the decryption of response data.
+// See: GlobalPlatform Card Specification Amendment D Section 6.2.6 APDU Data Field Encryption and Decryption
+func (s *scp03Session) icv(response bool) []byte {
+	ctr := make([]byte, aes.BlockSize)
+	binary.BigEndian.PutUint64(ctr[8:], s.counter)
+
+	if response {
+		ctr[0] = 0x80
+	}
+
+	icv := make([]byte, aes.BlockSize)
+	s.enc.Encrypt(icv, ctr)
+
+	return icv
+}
+
+// Wrap protects a command APDU.
+// See: GlobalPlatform Card Specification Amendment D Section 6.2.4 APDU Command C-MAC Generation and Verification
+func (s *scp03Session) Wrap(cmd *iso.CAPDU) (*iso.CAPDU, error) {
+	if !s.level.Has(SecurityLevelCMAC) {
+		return cmd, nil
+	}
+
+	cla, err := withSecureMessaging(cmd.Cla)
+	if err != nil {
+		return nil, err
+	}
+
+	data := cmd.Data
+
+	if s.level.Has(SecurityLevelCDEC) {
+		s.counter++
+
+		if len(data) > 0 {
+			padded := mac.Pad(data, aes.BlockSize)
+			data = make([]byte, len(padded))
+			cipher.NewCBCEncrypter(s.enc, s.icv(false)).CryptBlocks(data, padded)
+		}
+	}
+
+	ne := cmd.Ne
+	if s.level.Has(SecurityLevelRMAC) && ne > 0 {
+		ne = iso.MaxLenRespDataStandard
+		if cmd.Ne > iso.MaxLenRespDataStandard-lenMAC-aes.BlockSize {
+			ne = iso.MaxLenRespDataExtended
+		}
+	}
+
+	macInput := append([]byte{}, s.macChain...)
+	macInput = append(macInput, byte(cla), byte(cmd.Ins), cmd.P1, cmd.P2)
+	macInput = appendLc(macInput, len(data)+lenMAC, ne)
+	macInput = append(macInput, data...)
+
+	s.macChain = mac.CMAC(s.mac, macInput)
+
+	return &iso.CAPDU{
+		Cla:  cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: append(data, s.macChain[:lenMAC]...),
+		Ne:   ne,
+	}, nil
+}
+
+// Unwrap verifies and decrypts a protected response APDU.
+// See: GlobalPlatform Card Specification Amendment D Section 6.2.5 APDU Response R-MAC Generation and Verification
+func (s *scp03Session) Unwrap(resp *iso.RAPDU) (*iso.RAPDU, error) {
+	if !s.level.Has(SecurityLevelRMAC) {
+		return resp, nil
+	}
+
+	if len(resp.Data) < lenMAC {
+		// Errors are not protected by a R-MAC
+		if !resp.Code().IsSuccess() && len(resp.Data) == 0 {
+			return resp, nil
+		}
+
+		return nil, fmt.Errorf("%w: missing R-MAC", ErrInvalidResponse)
+	}
+
+	data := resp.Data[:len(resp.Data)-lenMAC]
+	rmac := resp.Data[len(resp.Data)-lenMAC:]
+
+	macInput := append([]byte{}, s.macChain...)
+	macInput = append(macInput, data...)
+	macInput = append(macInput, resp.SW1, resp.SW2)
+
+	if subtle.ConstantTimeCompare(rmac, mac.CMAC(s.rmac, macInput)[:lenMAC]) != 1 {
+		return nil, sm.ErrInvalidMAC
+	}
+
+	if s.level.Has(SecurityLevelRENC) && len(data) > 0 {
+		if len(data)%aes.BlockSize != 0 {
+			return nil, fmt.Errorf("%w: cryptogram is not a multiple of the block size", ErrInvalidResponse)
+		}
+
+		padded := make([]byte, len(data))
+		cipher.NewCBCDecrypter(s.enc, s.icv(true)).CryptBlocks(padded, data)
+
+		var err error
+		if data, err = mac.Unpad(padded); err != nil {
+			return nil, err
+		}
+	}
+
+	return &iso.RAPDU{
+		Data: data,
+		SW1:  resp.SW1,
+		SW2:  resp.SW2,
+	}, nil
+}
+
+// withSecureMessaging indicates GlobalPlatform secure messaging in the class byte.
+// See: GlobalPlatform Card Specification Section 11.1.4 Class Byte Coding
+func withSecureMessaging(cla iso.Class) (iso.Class, error) {
+	if smCla, err := cla.WithSecureMessaging(iso.SecureMessagingProprietary); err == nil {
+		return smCla, nil
+	}
+
+	// Further interindustry classes only have a single bit to indicate secure messaging
+	return cla.WithSecureMessaging(iso.SecureMessagingNoHeader)
+}
+
+// appendLc appends the encoding of Lc as it is used in the serialized command APDU.
+func appendLc(buf []byte, lc, ne int) []byte {
+	if lc > iso.MaxLenCmdDataStandard || ne > iso.MaxLenRespDataStandard {
+		return append(buf, 0x00, byte(lc>>8), byte(lc))
+	}
+
+	return append(buf, byte(lc))
+}
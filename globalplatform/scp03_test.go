@@ -0,0 +1,351 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform_test
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	gp "cunicu.li/go-iso7816/globalplatform"
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+// scp03SD is a card-side implementation of SCP03.
+type scp03SD struct {
+	keys gp.StaticKeys
+	i    byte
+	aid  []byte
+	seq  int
+
+	authenticated  bool
+	level          gp.SecurityLevel
+	hostCryptogram []byte
+
+	sENC, sMAC, sRMAC []byte
+	macChain          []byte
+	counter           byte
+}
+
+func kdf(key []byte, constant byte, context []byte, bits int) []byte {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+
+	var out []byte
+	for i := 1; len(out) < bits/8; i++ {
+		input := append(make([]byte, 11), constant, 0x00, byte(bits>>8), byte(bits), byte(i))
+		out = append(out, mac.CMAC(b, append(input, context...))...)
+	}
+
+	return out[:bits/8]
+}
+
+func (sd *scp03SD) icv(response bool) []byte {
+	ctr := make([]byte, aes.BlockSize)
+	ctr[15] = sd.counter
+	if response {
+		ctr[0] = 0x80
+	}
+
+	b, _ := aes.NewCipher(sd.sENC)
+	b.Encrypt(ctr, ctr)
+
+	return ctr
+}
+
+func (sd *scp03SD) process(cmd *iso.CAPDU, cmdBuf []byte) *iso.RAPDU {
+	if cmd.Ins == gp.InsInitializeUpdate {
+		return sd.initializeUpdate(cmd)
+	}
+
+	if cmd.Cla.SecureMessaging() == iso.SecureMessagingNone {
+		if sd.authenticated && sd.level != gp.SecurityLevelNone {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		return application(cmd)
+	}
+
+	if len(cmd.Data) < 8 || sd.sMAC == nil {
+		return status(iso.ErrIncorrectSecureMessagingDataObjects)
+	}
+
+	data, cmac := cmd.Data[:len(cmd.Data)-8], cmd.Data[len(cmd.Data)-8:]
+
+	b, _ := aes.NewCipher(sd.sMAC)
+	macChain := mac.CMAC(b, append(append(append([]byte{}, sd.macChain...), header(cmd, cmdBuf)...), data...))
+	if subtle.ConstantTimeCompare(cmac, macChain[:8]) != 1 {
+		sd.authenticated = false
+		return status(iso.ErrSecurityStatusNotSatisfied)
+	}
+
+	sd.macChain = macChain
+
+	if !sd.authenticated {
+		if cmd.Ins != gp.InsExternalAuthenticate || subtle.ConstantTimeCompare(data, sd.hostCryptogram) != 1 {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		sd.authenticated = true
+		sd.level = gp.SecurityLevel(cmd.P1)
+
+		return status(iso.ErrSuccess)
+	}
+
+	if sd.level.Has(gp.SecurityLevelCDEC) {
+		sd.counter++
+
+		if len(data) > 0 {
+			b, _ := aes.NewCipher(sd.sENC)
+			padded := make([]byte, len(data))
+			cipher.NewCBCDecrypter(b, sd.icv(false)).CryptBlocks(padded, data)
+
+			var err error
+			if data, err = mac.Unpad(padded); err != nil {
+				return status(iso.ErrIncorrectSecureMessagingDataObjects)
+			}
+		}
+	}
+
+	resp := application(&iso.CAPDU{
+		Cla:  cmd.Cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: data,
+	})
+
+	if sd.level.Has(gp.SecurityLevelRMAC) {
+		rdata := resp.Data
+
+		if sd.level.Has(gp.SecurityLevelRENC) && len(rdata) > 0 {
+			b, _ := aes.NewCipher(sd.sENC)
+			padded := mac.Pad(rdata, aes.BlockSize)
+			rdata = make([]byte, len(padded))
+			cipher.NewCBCEncrypter(b, sd.icv(true)).CryptBlocks(rdata, padded)
+		}
+
+		b, _ := aes.NewCipher(sd.sRMAC)
+		rmac := mac.CMAC(b, append(append(append([]byte{}, sd.macChain...), rdata...), resp.SW1, resp.SW2))
+		resp.Data = append(rdata, rmac[:8]...)
+	}
+
+	return resp
+}
+
+func (sd *scp03SD) initializeUpdate(cmd *iso.CAPDU) *iso.RAPDU {
+	if cmd.P1 != 0 && cmd.P1 != sd.keys.Version {
+		return status(iso.ErrReferenceNotFound)
+	}
+
+	var seq, cardChallenge []byte
+	if sd.i&0x10 != 0 {
+		sd.seq++
+		seq = []byte{byte(sd.seq >> 16), byte(sd.seq >> 8), byte(sd.seq)}
+		cardChallenge = kdf(sd.keys.Enc, 0x02, append(append([]byte{}, seq...), sd.aid...), 64)
+	} else {
+		cardChallenge = make([]byte, 8)
+		if _, err := rand.Read(cardChallenge); err != nil {
+			panic(err)
+		}
+	}
+
+	context := append(append([]byte{}, cmd.Data...), cardChallenge...)
+
+	sd.sENC = kdf(sd.keys.Enc, 0x04, context, 128)
+	sd.sMAC = kdf(sd.keys.MAC, 0x06, context, 128)
+	sd.sRMAC = kdf(sd.keys.MAC, 0x07, context, 128)
+	sd.hostCryptogram = kdf(sd.sMAC, 0x01, context, 64)
+	sd.macChain = make([]byte, 16)
+	sd.counter = 0
+	sd.authenticated = false
+	sd.level = gp.SecurityLevelNone
+
+	data := make([]byte, 10) // Key diversification data
+	data = append(data, sd.keys.Version, 0x03, sd.i)
+	data = append(data, cardChallenge...)
+	data = append(data, kdf(sd.sMAC, 0x00, context, 64)...)
+	data = append(data, seq...)
+
+	return &iso.RAPDU{Data: data, SW1: 0x90, SW2: 0x00}
+}
+
+func newSCP03Card(i byte) *iso.Card {
+	return iso.NewCard(&simCard{
+		sd: &scp03SD{
+			keys: gp.StaticKeys{
+				Version: 0x30,
+				Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+				MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+			},
+			i:   i,
+			aid: iso.AidCardManager,
+		},
+	})
+}
+
+func TestSCP03(t *testing.T) {
+	levels := []gp.SecurityLevel{
+		gp.SecurityLevelNone,
+		gp.SecurityLevelCMAC,
+		gp.SecurityLevelCMAC | gp.SecurityLevelCDEC,
+		gp.SecurityLevelCMAC | gp.SecurityLevelRMAC,
+		gp.SecurityLevelCMAC | gp.SecurityLevelCDEC | gp.SecurityLevelRMAC,
+		gp.SecurityLevelCMAC | gp.SecurityLevelCDEC | gp.SecurityLevelRMAC | gp.SecurityLevelRENC,
+	}
+
+	for _, level := range levels {
+		for _, i := range []byte{0x60, 0x70} {
+			t.Run(fmt.Sprintf("level=%02x/i=%02x", byte(level), i), func(t *testing.T) {
+				require := require.New(t)
+
+				scp, err := gp.OpenSCP03(newSCP03Card(i), gp.SCP03Config{
+					Keys: gp.StaticKeys{
+						Version: 0x30,
+						Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+						MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+					},
+					SecurityLevel: level,
+				})
+				require.NoError(err)
+
+				card := iso.NewCard(scp)
+
+				for _, n := range []int{0, 1, 16, 200} {
+					resp, err := card.Send(&iso.CAPDU{
+						Cla:  iso.ClassProprietary,
+						Ins:  insEcho,
+						Data: generate(n),
+						Ne:   iso.MaxLenRespDataStandard,
+					})
+					require.NoError(err)
+
+					if n == 0 {
+						require.Empty(resp)
+					} else {
+						require.Equal(generate(n), resp)
+					}
+				}
+
+				// Long responses are retrieved via GET RESPONSE
+				resp, err := card.Send(&iso.CAPDU{
+					Cla: iso.ClassProprietary,
+					Ins: insGenerate,
+					P1:  0x02,
+					P2:  0x58,
+					Ne:  iso.MaxLenRespDataStandard,
+				})
+				require.NoError(err)
+				require.Equal(generate(600), resp)
+
+				_, err = card.Send(&iso.CAPDU{
+					Cla: iso.ClassProprietary,
+					Ins: 0xFF,
+				})
+				require.ErrorIs(err, iso.ErrUnsupportedInstruction)
+			})
+		}
+	}
+}
+
+func TestSCP03Channel(t *testing.T) {
+	require := require.New(t)
+
+	sim := newSCP03Card(0x60).PCSCCard.(*simCard) //nolint:forcetypeassert
+	basic := iso.NewCard(sim)
+
+	card, err := basic.OpenChannel()
+	require.NoError(err)
+	require.Equal(1, card.Channel())
+
+	scp, err := gp.OpenSCP03(card, gp.SCP03Config{
+		Keys: gp.StaticKeys{
+			Version: 0x30,
+			Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+			MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: gp.SecurityLevelCMAC | gp.SecurityLevelCDEC | gp.SecurityLevelRMAC,
+	})
+	require.NoError(err)
+
+	scpCard := iso.NewCard(scp)
+	require.Equal(1, scpCard.Channel())
+
+	resp, err := scpCard.Send(&iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  insEcho,
+		Data: generate(16),
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	require.NoError(err)
+	require.Equal(generate(16), resp)
+
+	// All commands after MANAGE CHANNEL are sent on the logical channel
+	require.Equal([]int{0, 1, 1, 1}, sim.channels)
+
+	// The secure channel shares the lock of the logical channel
+	tx, err := card.NewTransaction()
+	require.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = scpCard.SendContext(ctx, &iso.CAPDU{
+		Cla: iso.ClassProprietary,
+		Ins: insEcho,
+	})
+	require.ErrorIs(err, context.DeadlineExceeded)
+	require.NoError(tx.Close())
+}
+
+func TestSCP03InvalidKeys(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP03(newSCP03Card(0x60), gp.SCP03Config{
+		Keys:          gp.DefaultKeys(),
+		SecurityLevel: gp.SecurityLevelCMAC,
+	})
+	require.ErrorIs(err, gp.ErrInvalidCryptogram)
+}
+
+func TestSCP03InvalidCardChallenge(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP03(newSCP03Card(0x70), gp.SCP03Config{
+		Keys: gp.StaticKeys{
+			Version: 0x30,
+			Enc:     unhex("404142434445464748494a4b4c4d4e4f"),
+			MAC:     unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: gp.SecurityLevelCMAC,
+		AID:           iso.AidOpenPGP,
+	})
+	require.ErrorIs(err, gp.ErrInvalidCardChallenge)
+}
+
+func TestSCP03InvalidSecurityLevel(t *testing.T) {
+	require := require.New(t)
+
+	_, err := gp.OpenSCP03(newSCP03Card(0x60), gp.SCP03Config{
+		Keys:          gp.DefaultKeys(),
+		SecurityLevel: gp.SecurityLevelCDEC,
+	})
+	require.ErrorIs(err, gp.ErrInvalidSecurityLevel)
+
+	_, err = gp.OpenSCP03(newSCP03Card(0x00), gp.SCP03Config{
+		Keys:          gp.DefaultKeys(),
+		SecurityLevel: gp.SecurityLevelCMAC | gp.SecurityLevelRMAC,
+	})
+	require.ErrorIs(err, gp.ErrInvalidSecurityLevel)
+}
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/internal/mac"
+	"cunicu.li/go-iso7816/test"
+)
+
+// The known-answer tests use fixed challenges to check the session key derivation,
+// the authentication cryptograms and the protection of commands and responses.
+// The expected values have been computed independently with the OpenSSL command line tools.
+// In addition, the inputs of the key derivation are spelled out according to the
+// data derivation scheme of GlobalPlatform Amendment D to check their layout.
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// cmac computes the AES-CMAC of a hex encoded message.
+func cmac(key []byte, msg string) []byte {
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return mac.CMAC(b, unhex(msg))
+}
+
+// withHostChallenge replaces the source of host challenges for the duration of the test.
+func withHostChallenge(t *testing.T, hostChallenge []byte) {
+	t.Helper()
+
+	randReader = bytes.NewReader(hostChallenge)
+
+	t.Cleanup(func() {
+		randReader = rand.Reader
+	})
+}
+
+func wrap(t *testing.T, s levelWrapper, cmd *iso.CAPDU) []byte {
+	t.Helper()
+
+	wcmd, err := s.Wrap(cmd)
+	require.NoError(t, err)
+
+	buf, err := wcmd.Bytes()
+	require.NoError(t, err)
+
+	return buf
+}
+
+func TestSCP03Vectors(t *testing.T) {
+	require := require.New(t)
+
+	keys := StaticKeys{
+		Enc: unhex("404142434445464748494a4b4c4d4e4f"),
+		MAC: unhex("505152535455565758595a5b5c5d5e5f"),
+	}
+
+	hostChallenge := unhex("a0a1a2a3a4a5a6a7")
+	cardChallenge := unhex("b459d14e29e4dd17")
+
+	// Pseudo-random card challenge
+	err := verifySCP03CardChallenge(SCP03Config{Keys: keys}, cardChallenge, unhex("00002a"))
+	require.NoError(err)
+
+	// Label || derivation constant || separation indicator || L || i || sequence counter || AID
+	require.Equal(cardChallenge, cmac(keys.Enc, "0000000000000000000000"+"02"+"00"+"0040"+"01"+"00002a"+"a000000151000000")[:8])
+
+	derivationContext := append(append([]byte{}, hostChallenge...), cardChallenge...)
+
+	sess, err := newSCP03SessionFromStaticKeys(keys, derivationContext)
+	require.NoError(err)
+	require.Equal(unhex("b91e155b4ead69a1ddd565816621c12e"), sess.sENC)
+	require.Equal(unhex("a604d9f90cafd9c6342864d52dfe0d35"), sess.sMAC)
+	require.Equal(unhex("36161fa6504dd019c6130102709c27ab"), sess.sRMAC)
+
+	// Label || derivation constant || separation indicator || L || i || host challenge || card challenge
+	require.Equal(sess.sENC, cmac(keys.Enc, "0000000000000000000000"+"04"+"00"+"0080"+"01"+"a0a1a2a3a4a5a6a7"+"b459d14e29e4dd17"))
+	require.Equal(sess.sMAC, cmac(keys.MAC, "0000000000000000000000"+"06"+"00"+"0080"+"01"+"a0a1a2a3a4a5a6a7"+"b459d14e29e4dd17"))
+	require.Equal(sess.sRMAC, cmac(keys.MAC, "0000000000000000000000"+"07"+"00"+"0080"+"01"+"a0a1a2a3a4a5a6a7"+"b459d14e29e4dd17"))
+
+	cardCryptogram, err := scp03KDF(sess.sMAC, derivationCardCryptogram, derivationContext, 64)
+	require.NoError(err)
+	require.Equal(unhex("c55a1aaaeb9966c0"), cardCryptogram)
+	require.Equal(cardCryptogram, cmac(sess.sMAC, "0000000000000000000000"+"00"+"00"+"0040"+"01"+"a0a1a2a3a4a5a6a7"+"b459d14e29e4dd17")[:8])
+
+	hostCryptogram, err := scp03KDF(sess.sMAC, derivationHostCryptogram, derivationContext, 64)
+	require.NoError(err)
+	require.Equal(unhex("90e66f655b2aff18"), hostCryptogram)
+	require.Equal(hostCryptogram, cmac(sess.sMAC, "0000000000000000000000"+"01"+"00"+"0040"+"01"+"a0a1a2a3a4a5a6a7"+"b459d14e29e4dd17")[:8])
+
+	level := SecurityLevelCMAC | SecurityLevelCDEC | SecurityLevelRMAC | SecurityLevelRENC
+
+	require.Equal(unhex("848233001090e66f655b2aff180af55e79a178baef"), wrap(t, sess, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  InsExternalAuthenticate,
+		P1:   byte(level),
+		Data: hostCryptogram,
+	}))
+
+	sess.setSecurityLevel(level)
+
+	// C-DEC with a counter of 1 and C-MAC chained to EXTERNAL AUTHENTICATE
+	require.Equal(unhex("84e280002819f01e2c58f04b38df8b9bef45886f4031582f4f7f958fb16a0051e2186515f451832f97adfa021f00"), wrap(t, sess, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  0xE2,
+		P1:   0x80,
+		Data: unhex("0102030405060708090a0b0c0d0e0f1011"),
+		Ne:   32,
+	}))
+
+	// R-ENC and R-MAC chained to the C-MAC of the command
+	resp, err := sess.Unwrap(&iso.RAPDU{
+		Data: unhex("ea1354e9db5082459624b2850b8a8b66b7a12e1773e60e87"),
+		SW1:  0x90,
+		SW2:  0x00,
+	})
+	require.NoError(err)
+	require.Equal(unhex("cafe"), resp.Data)
+
+	// The counter is incremented for commands without data as well
+	require.Equal(unhex("84ca00ff0803e54903f805f1f600"), wrap(t, sess, &iso.CAPDU{
+		Cla: iso.ClassProprietary,
+		Ins: iso.InsGetData,
+		P2:  0xFF,
+		Ne:  32,
+	}))
+
+	resp, err = sess.Unwrap(&iso.RAPDU{
+		Data: unhex("30402ba3711f302e"),
+		SW1:  0x6A,
+		SW2:  0x88,
+	})
+	require.NoError(err)
+	require.Empty(resp.Data)
+	require.Equal(iso.ErrReferenceNotFound, resp.Code())
+
+	require.Equal(unhex("84e2800118efa2e9d6f86f6dd03a1a23218794682a3166fe1ab414f8e300"), wrap(t, sess, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  0xE2,
+		P1:   0x80,
+		P2:   0x01,
+		Data: unhex("aabbcc"),
+		Ne:   32,
+	}))
+
+	// Responses with an invalid R-MAC are rejected
+	_, err = sess.Unwrap(&iso.RAPDU{
+		Data: unhex("30402ba3711f302e"),
+		SW1:  0x6A,
+		SW2:  0x88,
+	})
+	require.Error(err)
+}
+
+// TestSCP03Transcript opens a session with the fixed challenges of TestSCP03Vectors
+// to check the encoding of the exchanged APDUs including the order of the challenges.
+func TestSCP03Transcript(t *testing.T) {
+	require := require.New(t)
+
+	withHostChallenge(t, unhex("a0a1a2a3a4a5a6a7"))
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	smCard, err := OpenSCP03(iso.NewCard(mockCard), SCP03Config{
+		Keys: StaticKeys{
+			Enc: unhex("404142434445464748494a4b4c4d4e4f"),
+			MAC: unhex("505152535455565758595a5b5c5d5e5f"),
+		},
+		SecurityLevel: SecurityLevelCMAC | SecurityLevelCDEC | SecurityLevelRMAC | SecurityLevelRENC,
+	})
+	require.NoError(err)
+
+	card := iso.NewCard(smCard)
+
+	resp, err := card.Send(&iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  0xE2,
+		P1:   0x80,
+		Data: unhex("0102030405060708090a0b0c0d0e0f1011"),
+		Ne:   32,
+	})
+	require.NoError(err)
+	require.Equal(unhex("cafe"), resp)
+
+	_, err = card.Send(&iso.CAPDU{
+		Cla: iso.ClassProprietary,
+		Ins: iso.InsGetData,
+		P2:  0xFF,
+		Ne:  32,
+	})
+	require.ErrorIs(err, iso.ErrReferenceNotFound)
+
+	require.NoError(mockCard.Close())
+}
@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+	"cunicu.li/go-iso7816/internal/mac"
+	"cunicu.li/go-iso7816/sm"
+)
+
+var (
+	ErrInvalidReceipt = errors.New("invalid receipt")
+	ErrMissingOCE     = errors.New("missing off-card entity key or certificates")
+)
+
+// Data objects of the key agreement
+// See: GlobalPlatform Card Specification Amendment F Section 7.6.2 MUTUAL AUTHENTICATE
+const (
+	TagSCPIdentifier      tlv.Tag = 0x90
+	TagKeyType            tlv.Tag = 0x80
+	TagKeyLength          tlv.Tag = 0x81
+	TagEphemeralPublicKey tlv.Tag = 0x5F49
+	TagReceipt            tlv.Tag = 0x86
+)
+
+const (
+	keyUsageSCP11 byte = 0x3C // C-MAC, C-DECRYPTION, R-MAC and R-ENCRYPTION
+	keyTypeAES    byte = 0x88
+	lenSessionKey      = 16
+)
+
+// SCP11Variant is the variant of SCP11.
+// Its value is the key identifier of the security domain key set.
+type SCP11Variant byte
+
+const (
+	SCP11a SCP11Variant = 0x11 // Mutual authentication
+	SCP11b SCP11Variant = 0x13 // Card authentication only
+	SCP11c SCP11Variant = 0x15 // Mutual authentication with support for offline scripting
+)
+
+func (v SCP11Variant) String() string {
+	switch v {
+	case SCP11a:
+		return "SCP11a"
+	case SCP11b:
+		return "SCP11b"
+	case SCP11c:
+		return "SCP11c"
+	default:
+		return "<unknown>"
+	}
+}
+
+// parameter returns the i parameter of the SCP identifier.
+func (v SCP11Variant) parameter() (byte, error) {
+	switch v {
+	case SCP11a:
+		return 0x01, nil
+	case SCP11b:
+		return 0x00, nil
+	case SCP11c:
+		return 0x03, nil
+	default:
+		return 0, fmt.Errorf("%w: SCP11 variant %#02x", ErrUnsupportedProtocol, byte(v))
+	}
+}
+
+// KeyRef references a key by its identifier and version.
+type KeyRef struct {
+	ID      byte
+	Version byte
+}
+
+// SCP11Config contains the parameters for opening a SCP11 secure channel.
+type SCP11Config struct {
+	// Variant selects SCP11a, SCP11b or SCP11c.
+	Variant SCP11Variant
+
+	// KeyVersion is the version of the security domain key set.
+	KeyVersion byte
+
+	// SDPublicKey is the static public key of the security domain (PK.SD.ECKA).
+	// It can be retrieved from the certificate store using GetCertificates()
+	// and should be verified by the caller.
+	SDPublicKey *ecdsa.PublicKey
+
+	// OCEPrivateKey is the static private key of the off-card entity (SK.OCE.ECKA).
+	// It is required for SCP11a and SCP11c only.
+	OCEPrivateKey *ecdsa.PrivateKey
+
+	// OCECertificates is the certificate chain of the off-card entity
+	// whose last certificate contains the public key of OCEPrivateKey.
+	// It is required for SCP11a and SCP11c only.
+	OCECertificates []*Certificate
+
+	// OCEKeyRef references the key of the security domain which is used
+	// to verify the first certificate of OCECertificates.
+	OCEKeyRef KeyRef
+}
+
+// OpenSCP11 opens a SCP11 secure channel session with the currently selected security domain.
+// The session keys are agreed using ECDH with ephemeral and static keys and
+// all following APDUs are protected by the secure messaging of SCP03.
+// See: GlobalPlatform Card Specification Amendment F
+func OpenSCP11(card *iso.Card, cfg SCP11Config) (*sm.Card, error) {
+	return OpenSCP11Context(context.Background(), card, cfg)
+}
+
+// OpenSCP11Context is like OpenSCP11 but allows to pass a context.
+func OpenSCP11Context(ctx context.Context, card *iso.Card, cfg SCP11Config) (*sm.Card, error) {
+	param, err := cfg.Variant.parameter()
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SDPublicKey == nil {
+		return nil, fmt.Errorf("%w: missing public key of security domain", ErrInvalidKey)
+	}
+
+	pkSD, err := cfg.SDPublicKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	// The static key of the off-card entity is only used for SCP11a and SCP11c
+	var skOCE *ecdh.PrivateKey
+
+	ins := iso.InsInternalAuthenticate
+	if cfg.Variant != SCP11b {
+		ins = InsMutualAuthenticate
+
+		if cfg.OCEPrivateKey == nil || len(cfg.OCECertificates) == 0 {
+			return nil, ErrMissingOCE
+		}
+
+		if skOCE, err = cfg.OCEPrivateKey.ECDH(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+		}
+
+		if err := uploadCertificates(ctx, card, cfg.OCEKeyRef, cfg.OCECertificates); err != nil {
+			return nil, err
+		}
+	}
+
+	eskOCE, err := pkSD.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+
+	if skOCE == nil {
+		skOCE = eskOCE
+	}
+
+	data, err := tlv.EncodeBER(
+		tlv.New(TagControlReference,
+			tlv.New(TagSCPIdentifier, byte(0x11), param),
+			tlv.New(TagKeyUsage, keyUsageSCP11),
+			tlv.New(TagKeyType, keyTypeAES),
+			tlv.New(TagKeyLength, byte(lenSessionKey)),
+		),
+		tlv.New(TagEphemeralPublicKey, eskOCE.PublicKey().Bytes()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := card.SendContext(ctx, &iso.CAPDU{
+		Cla:  iso.ClassProprietary,
+		Ins:  ins,
+		P1:   cfg.KeyVersion,
+		P2:   byte(cfg.Variant),
+		Data: data,
+		Ne:   iso.MaxLenRespDataStandard,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	tag, epkSDBuf, rest, err := nextTLV(resp)
+	if err != nil || tag != TagEphemeralPublicKey {
+		return nil, fmt.Errorf("%w: missing ephemeral public key", ErrInvalidResponse)
+	}
+
+	// The receipt is computed over the key agreement data including the
+	// complete data object of the ephemeral public key of the security domain
+	keyAgreementData := append(append([]byte{}, data...), resp[:len(resp)-len(rest)]...)
+
+	tag, receipt, _, err := nextTLV(rest)
+	if err != nil || tag != TagReceipt {
+		return nil, fmt.Errorf("%w: missing receipt", ErrInvalidResponse)
+	}
+
+	epkSD, err := pkSD.Curve().NewPublicKey(epkSDBuf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	shSee, err := eskOCE.ECDH(epkSD)
+	if err != nil {
+		return nil, err
+	}
+
+	shSes, err := skOCE.ECDH(pkSD)
+	if err != nil {
+		return nil, err
+	}
+
+	// Receipt key, S-ENC, S-MAC, S-RMAC and S-DEK
+	keys := x963KDF(sha256.New, append(shSee, shSes...),
+		[]byte{keyUsageSCP11, keyTypeAES, lenSessionKey}, 5*lenSessionKey)
+
+	receiptKey, err := aes.NewCipher(keys[:lenSessionKey])
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(receipt, mac.CMAC(receiptKey, keyAgreementData)) != 1 {
+		return nil, ErrInvalidReceipt
+	}
+
+	sess, err := newSCP03Session(
+		keys[1*lenSessionKey:2*lenSessionKey],
+		keys[2*lenSessionKey:3*lenSessionKey],
+		keys[3*lenSessionKey:4*lenSessionKey],
+		receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	sess.setSecurityLevel(SecurityLevelCMAC | SecurityLevelCDEC | SecurityLevelRMAC | SecurityLevelRENC)
+
+	return sm.NewCard(card, sess), nil
+}
+
+// uploadCertificates provides the certificate chain of the off-card entity to the security domain.
+// See: GlobalPlatform Card Specification Amendment F Section 7.5 PERFORM SECURITY OPERATION
+func uploadCertificates(ctx context.Context, card *iso.Card, ref KeyRef, certs []*Certificate) error {
+	for i, cert := range certs {
+		p2 := ref.ID
+		if i < len(certs)-1 {
+			p2 |= 0x80 // More certificates follow
+		}
+
+		if _, err := card.SendContext(ctx, &iso.CAPDU{
+			Cla:  iso.ClassProprietary,
+			Ins:  iso.InsPerformSecurityOperation,
+			P1:   ref.Version,
+			P2:   p2,
+			Data: cert.Raw,
+		}); err != nil {
+			return fmt.Errorf("failed to upload certificate %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// x963KDF implements the key derivation function of ANSI X9.63.
+// See: BSI TR-03111 Section 4.3.3 X9.63 Key Derivation Function
+func x963KDF(newHash func() hash.Hash, z, sharedInfo []byte, n int) []byte {
+	var out []byte
+
+	for counter := uint32(1); len(out) < n; counter++ {
+		d := newHash()
+		d.Write(z)
+		d.Write(binary.BigEndian.AppendUint32(nil, counter))
+		d.Write(sharedInfo)
+
+		out = d.Sum(out)
+	}
+
+	return out[:n]
+}
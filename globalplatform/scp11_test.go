@@ -0,0 +1,351 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package globalplatform_test
+
+import (
+	"crypto/aes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+	gp "cunicu.li/go-iso7816/globalplatform"
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+// scp11SD is a card-side implementation of SCP11.
+type scp11SD struct {
+	kvn   byte
+	sk    *ecdsa.PrivateKey
+	certs []byte
+
+	oceCA    *ecdsa.PublicKey
+	oceChain [][]byte
+	pkOCE    *ecdh.PublicKey
+
+	session *scp03SD
+}
+
+func (sd *scp11SD) process(cmd *iso.CAPDU, cmdBuf []byte) *iso.RAPDU {
+	if sd.session != nil && cmd.Cla.SecureMessaging() != iso.SecureMessagingNone {
+		return sd.session.process(cmd, cmdBuf)
+	}
+
+	switch cmd.Ins {
+	case iso.InsGetData:
+		if cmd.P1 != 0xBF || cmd.P2 != 0x21 {
+			return status(iso.ErrReferenceNotFound)
+		}
+
+		return &iso.RAPDU{Data: sd.certs, SW1: 0x90, SW2: 0x00}
+
+	case iso.InsPerformSecurityOperation:
+		sd.oceChain = append(sd.oceChain, cmd.Data)
+		if cmd.P2&0x80 != 0 {
+			return status(iso.ErrSuccess)
+		}
+
+		cert, err := gp.ParseCertificate(sd.oceChain[len(sd.oceChain)-1])
+		sd.oceChain = nil
+		if err != nil {
+			return status(iso.ErrIncorrectData)
+		}
+
+		if err := cert.CheckSignature(sd.oceCA); err != nil {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		if sd.pkOCE, err = cert.PublicKey.ECDH(); err != nil {
+			return status(iso.ErrIncorrectData)
+		}
+
+		return status(iso.ErrSuccess)
+
+	case iso.InsInternalAuthenticate, iso.InsExternalOrMutualAuthenticate:
+		return sd.authenticate(cmd)
+
+	default:
+		if sd.session != nil {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		return application(cmd)
+	}
+}
+
+func (sd *scp11SD) authenticate(cmd *iso.CAPDU) *iso.RAPDU {
+	if cmd.P1 != sd.kvn {
+		return status(iso.ErrReferenceNotFound)
+	}
+
+	tvs, err := tlv.DecodeBER(cmd.Data)
+	if err != nil {
+		return status(iso.ErrIncorrectData)
+	}
+
+	epkOCEBuf, _, ok := tvs.Get(0x5F49)
+	if !ok {
+		return status(iso.ErrIncorrectData)
+	}
+
+	epkOCE, err := ecdh.P256().NewPublicKey(epkOCEBuf)
+	if err != nil {
+		return status(iso.ErrIncorrectData)
+	}
+
+	pkOCE := epkOCE
+	if cmd.P2 != byte(gp.SCP11b) {
+		if sd.pkOCE == nil {
+			return status(iso.ErrSecurityStatusNotSatisfied)
+		}
+
+		pkOCE = sd.pkOCE
+	}
+
+	skSD, _ := sd.sk.ECDH()
+
+	eskSD, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	shSee, _ := eskSD.ECDH(epkOCE)
+	shSes, _ := skSD.ECDH(pkOCE)
+
+	var keys []byte
+	for i := uint32(1); len(keys) < 80; i++ {
+		h := sha256.New()
+		h.Write(shSee)
+		h.Write(shSes)
+		h.Write(binary.BigEndian.AppendUint32(nil, i))
+		h.Write([]byte{0x3C, 0x88, 0x10})
+		keys = h.Sum(keys)
+	}
+
+	epkSD, _ := tlv.EncodeBER(tlv.New(0x5F49, eskSD.PublicKey().Bytes()))
+
+	b, _ := aes.NewCipher(keys[:16])
+	receipt := mac.CMAC(b, append(append([]byte{}, cmd.Data...), epkSD...))
+
+	sd.session = &scp03SD{
+		authenticated: true,
+		level:         gp.SecurityLevelCMAC | gp.SecurityLevelCDEC | gp.SecurityLevelRMAC | gp.SecurityLevelRENC,
+		sENC:          keys[16:32],
+		sMAC:          keys[32:48],
+		sRMAC:         keys[48:64],
+		macChain:      receipt,
+	}
+
+	receiptTLV, _ := tlv.EncodeBER(tlv.New(0x86, receipt))
+
+	return &iso.RAPDU{Data: append(epkSD, receiptTLV...), SW1: 0x90, SW2: 0x00}
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	sk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return sk
+}
+
+// gpCertificate creates a certificate in the GlobalPlatform format.
+func gpCertificate(t *testing.T, subject string, pub *ecdsa.PublicKey, ca *ecdsa.PrivateKey) []byte {
+	require := require.New(t)
+
+	pk, err := pub.ECDH()
+	require.NoError(err)
+
+	body, err := tlv.EncodeBER(
+		tlv.New(gp.TagSerialNumber, byte(0x01)),
+		tlv.New(gp.TagCAIdentifier, "CA"),
+		tlv.New(gp.TagSubjectIdentifier, subject),
+		tlv.New(gp.TagKeyUsage, byte(0x00), byte(0x80)),
+		tlv.New(gp.TagPublicKey,
+			tlv.New(gp.TagPublicKeyQ, pk.Bytes()),
+			tlv.New(gp.TagKeyParameterRef, gp.KeyParameterP256),
+		),
+	)
+	require.NoError(err)
+
+	digest := sha256.Sum256(body)
+	r, s, err := ecdsa.Sign(rand.Reader, ca, digest[:])
+	require.NoError(err)
+
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+
+	sigTLV, err := tlv.EncodeBER(tlv.New(gp.TagSignature, sig))
+	require.NoError(err)
+
+	cert, err := tlv.EncodeBER(tlv.New(gp.TagCertificate, body, sigTLV))
+	require.NoError(err)
+
+	return cert
+}
+
+// x509Certificate creates a certificate in the X.509 format.
+func x509Certificate(t *testing.T, subject string, pub *ecdsa.PublicKey, ca *ecdsa.PrivateKey) []byte {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: subject},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, ca)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func TestSCP11(t *testing.T) {
+	for _, variant := range []gp.SCP11Variant{gp.SCP11a, gp.SCP11b, gp.SCP11c} {
+		t.Run(variant.String(), func(t *testing.T) {
+			require := require.New(t)
+
+			sdCA := generateKey(t)
+			skSD := generateKey(t)
+			oceCA := generateKey(t)
+			skOCE := generateKey(t)
+
+			sdCert := gpCertificate(t, "SD", &skSD.PublicKey, sdCA)
+			sdCertStore, err := tlv.EncodeBER(tlv.New(gp.TagCertificateStore, sdCert))
+			require.NoError(err)
+
+			card := iso.NewCard(&simCard{
+				sd: &scp11SD{
+					kvn:   0x01,
+					sk:    skSD,
+					certs: sdCertStore,
+					oceCA: &oceCA.PublicKey,
+				},
+			})
+
+			certs, err := gp.GetCertificates(card, byte(variant), 0x01)
+			require.NoError(err)
+			require.Len(certs, 1)
+			require.NoError(certs[0].CheckSignature(&sdCA.PublicKey))
+			require.Equal([]byte("SD"), certs[0].SubjectIdentifier)
+
+			oceCert, err := gp.ParseCertificate(gpCertificate(t, "OCE", &skOCE.PublicKey, oceCA))
+			require.NoError(err)
+
+			scp, err := gp.OpenSCP11(card, gp.SCP11Config{
+				Variant:         variant,
+				KeyVersion:      0x01,
+				SDPublicKey:     certs[0].PublicKey,
+				OCEPrivateKey:   skOCE,
+				OCECertificates: []*gp.Certificate{oceCert},
+				OCEKeyRef:       gp.KeyRef{ID: 0x10, Version: 0x01},
+			})
+			require.NoError(err)
+
+			scpCard := iso.NewCard(scp)
+
+			resp, err := scpCard.Send(&iso.CAPDU{
+				Cla:  iso.ClassProprietary,
+				Ins:  insEcho,
+				Data: generate(100),
+				Ne:   iso.MaxLenRespDataStandard,
+			})
+			require.NoError(err)
+			require.Equal(generate(100), resp)
+
+			resp, err = scpCard.Send(&iso.CAPDU{
+				Cla: iso.ClassProprietary,
+				Ins: insGenerate,
+				P1:  0x02,
+				P2:  0x58,
+				Ne:  iso.MaxLenRespDataStandard,
+			})
+			require.NoError(err)
+			require.Equal(generate(600), resp)
+		})
+	}
+}
+
+func TestSCP11InvalidOCECertificate(t *testing.T) {
+	require := require.New(t)
+
+	skSD := generateKey(t)
+	skOCE := generateKey(t)
+
+	card := iso.NewCard(&simCard{
+		sd: &scp11SD{
+			kvn:   0x01,
+			sk:    skSD,
+			oceCA: &generateKey(t).PublicKey,
+		},
+	})
+
+	// Certificate is not signed by the CA known to the security domain
+	oceCert, err := gp.ParseCertificate(gpCertificate(t, "OCE", &skOCE.PublicKey, generateKey(t)))
+	require.NoError(err)
+
+	_, err = gp.OpenSCP11(card, gp.SCP11Config{
+		Variant:         gp.SCP11a,
+		KeyVersion:      0x01,
+		SDPublicKey:     &skSD.PublicKey,
+		OCEPrivateKey:   skOCE,
+		OCECertificates: []*gp.Certificate{oceCert},
+	})
+	require.ErrorIs(err, iso.ErrSecurityStatusNotSatisfied)
+
+	_, err = gp.OpenSCP11(card, gp.SCP11Config{
+		Variant:     gp.SCP11c,
+		KeyVersion:  0x01,
+		SDPublicKey: &skSD.PublicKey,
+	})
+	require.ErrorIs(err, gp.ErrMissingOCE)
+}
+
+func TestSCP11InvalidReceipt(t *testing.T) {
+	require := require.New(t)
+
+	card := iso.NewCard(&simCard{
+		sd: &scp11SD{
+			kvn: 0x01,
+			sk:  generateKey(t),
+		},
+	})
+
+	// The security domain uses a different static key
+	_, err := gp.OpenSCP11(card, gp.SCP11Config{
+		Variant:     gp.SCP11b,
+		KeyVersion:  0x01,
+		SDPublicKey: &generateKey(t).PublicKey,
+	})
+	require.ErrorIs(err, gp.ErrInvalidReceipt)
+}
+
+func TestCertificateX509(t *testing.T) {
+	require := require.New(t)
+
+	ca := generateKey(t)
+	sk := generateKey(t)
+
+	certs, err := gp.ParseCertificates(append(
+		x509Certificate(t, "CA", &ca.PublicKey, ca),
+		x509Certificate(t, "SD", &sk.PublicKey, ca)...))
+	require.NoError(err)
+	require.Len(certs, 2)
+
+	require.NotNil(certs[1].X509)
+	require.Equal("SD", certs[1].X509.Subject.CommonName)
+	require.True(certs[1].PublicKey.Equal(&sk.PublicKey))
+	require.NoError(certs[1].CheckSignature(certs[0].PublicKey))
+	require.ErrorIs(certs[1].CheckSignature(&sk.PublicKey), gp.ErrInvalidSignature)
+}
@@ -23,7 +23,7 @@ const (
 	InsDeactivateFile                 Instruction = 0x04 // Part 9
 	InsEraseRecord                    Instruction = 0x0C // Section 7.3.8
 	InsEraseBinary                    Instruction = 0x0E // Section 7.2.7
-	InsEraseBinaryEven                Instruction = 0x0F // Section 7.2.7
+	InsEraseBinaryOdd                 Instruction = 0x0F // Section 7.2.7
 	InsPerformSCQLOperation           Instruction = 0x10 // Part 7
 	InsPerformTransactionOperation    Instruction = 0x12 // Part 7
 	InsPerformUserOperation           Instruction = 0x14 // Part 7
@@ -40,7 +40,8 @@ const (
 	InsManageChannel                  Instruction = 0x70 // Section 7.1.2
 	InsExternalOrMutualAuthenticate   Instruction = 0x82 // Section 7.5.4
 	InsGetChallenge                   Instruction = 0x84 // Section 7.5.3
-	InsGeneralAuthenticate            Instruction = 0x87 // Section 7.5.5
+	InsGeneralAuthenticate            Instruction = 0x86 // Section 7.5.5
+	InsGeneralAuthenticateOdd         Instruction = 0x87 // Section 7.5.5
 	InsInternalAuthenticate           Instruction = 0x88 // Section 7.5.2
 	InsSearchBinary                   Instruction = 0xA0 // Section 7.2.6
 	InsSearchBinaryOdd                Instruction = 0xA1 // Section 7.2.6
@@ -48,7 +49,8 @@ const (
 	InsSelect                         Instruction = 0xA4 // Section 7.1.1
 	InsReadBinary                     Instruction = 0xB0 // Section 7.2.3
 	InsReadBinaryOdd                  Instruction = 0xB1 // Section 7.2.3
-	InsReadRecord                     Instruction = 0xB3 // Section 7.3.3
+	InsReadRecord                     Instruction = 0xB2 // Section 7.3.3
+	InsReadRecordOdd                  Instruction = 0xB3 // Section 7.3.3
 	InsGetResponse                    Instruction = 0xC0 // Section 7.6.1
 	InsEnvelope                       Instruction = 0xC2 // Section 7.6.2
 	InsEnvelopeOdd                    Instruction = 0xC3 // Section 7.6.2
@@ -57,7 +59,8 @@ const (
 	InsWriteBinary                    Instruction = 0xD0 // Section 7.2.6
 	InsWriteBinaryOdd                 Instruction = 0xD1 // Section 7.2.6
 	InsWriteRecord                    Instruction = 0xD2 // Section 7.3.4
-	InsUpdateBinary                   Instruction = 0xD7 // Section 7.2.5
+	InsUpdateBinary                   Instruction = 0xD6 // Section 7.2.5
+	InsUpdateBinaryOdd                Instruction = 0xD7 // Section 7.2.5
 	InsPutData                        Instruction = 0xDA // Section 7.4.3
 	InsPutDataOdd                     Instruction = 0xDB // Section 7.4.3
 	InsUpdateRecord                   Instruction = 0xDC // Section 7.3.5
@@ -69,3 +72,6 @@ const (
 	InsTerminateEF                    Instruction = 0xE8 // Part 9
 	InsTerminateCardUsage             Instruction = 0xFE // Part 9
 )
+
+// Deprecated: Use InsEraseBinaryOdd instead.
+const InsEraseBinaryEven = InsEraseBinaryOdd
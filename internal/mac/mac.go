@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mac implements message authentication codes and padding
+// schemes used by secure messaging and secure channel protocols.
+package mac
+
+import (
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidPadding = errors.New("invalid padding")
+	ErrInvalidLength  = errors.New("invalid length")
+)
+
+// Pad pads the message to a multiple of the block size.
+// See: ISO 9797-1 Padding method 2
+func Pad(msg []byte, blockSize int) []byte {
+	padded := make([]byte, len(msg), len(msg)+blockSize-len(msg)%blockSize)
+	copy(padded, msg)
+	padded = append(padded, 0x80)
+
+	for len(padded)%blockSize != 0 {
+		padded = append(padded, 0x00)
+	}
+
+	return padded
+}
+
+// Unpad removes padding from the message.
+// See: ISO 9797-1 Padding method 2
+func Unpad(msg []byte) ([]byte, error) {
+	for i := len(msg) - 1; i >= 0; i-- {
+		switch msg[i] {
+		case 0x80:
+			return msg[:i], nil
+		case 0x00:
+		default:
+			return nil, ErrInvalidPadding
+		}
+	}
+
+	return nil, ErrInvalidPadding
+}
+
+// CMAC computes the cipher-based message authentication code.
+// See: NIST SP 800-38B
+func CMAC(b cipher.Block, msg []byte) []byte {
+	bs := b.BlockSize()
+
+	k1, k2 := cmacSubkeys(b)
+
+	n := (len(msg) + bs - 1) / bs
+	complete := n > 0 && len(msg)%bs == 0
+	if n == 0 {
+		n = 1
+	}
+
+	last := make([]byte, bs)
+	if complete {
+		copy(last, msg[(n-1)*bs:])
+		xor(last, k1)
+	} else {
+		copy(last, Pad(msg[(n-1)*bs:], bs))
+		xor(last, k2)
+	}
+
+	x := make([]byte, bs)
+	for i := 0; i < n-1; i++ {
+		xor(x, msg[i*bs:(i+1)*bs])
+		b.Encrypt(x, x)
+	}
+
+	xor(x, last)
+	b.Encrypt(x, x)
+
+	return x
+}
+
+func cmacSubkeys(b cipher.Block) (k1, k2 []byte) {
+	bs := b.BlockSize()
+
+	rb := byte(0x87)
+	if bs == 8 {
+		rb = 0x1B
+	}
+
+	l := make([]byte, bs)
+	b.Encrypt(l, l)
+
+	k1 = shiftLeft(l, rb)
+	k2 = shiftLeft(k1, rb)
+
+	return k1, k2
+}
+
+func shiftLeft(in []byte, rb byte) []byte {
+	out := make([]byte, len(in))
+
+	var carry byte
+	for i := len(in) - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+
+	if carry != 0 {
+		out[len(out)-1] ^= rb
+	}
+
+	return out
+}
+
+// Retail computes the retail MAC of a padded message using a double-length DES key.
+// See: ISO 9797-1 MAC algorithm 3
+func Retail(key, icv, msg []byte) ([]byte, error) {
+	if len(key) != 16 {
+		return nil, fmt.Errorf("%w: retail MAC requires a 16 byte key", ErrInvalidLength)
+	}
+
+	if len(msg)%des.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: message is not padded", ErrInvalidLength)
+	}
+
+	k1, err := des.NewCipher(key[:8]) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	k2, err := des.NewCipher(key[8:16]) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	x := make([]byte, des.BlockSize)
+	if icv != nil {
+		copy(x, icv)
+	}
+
+	for i := 0; i < len(msg); i += des.BlockSize {
+		xor(x, msg[i:i+des.BlockSize])
+		k1.Encrypt(x, x)
+	}
+
+	k2.Decrypt(x, x)
+	k1.Encrypt(x, x)
+
+	return x, nil
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package mac_test
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+// Test vectors from NIST SP 800-38B Appendix D.1
+func TestCMAC(t *testing.T) {
+	msg := unhex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+	tests := []struct {
+		len int
+		mac string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+
+	b, err := aes.NewCipher(unhex("2b7e151628aed2a6abf7158809cf4f3c"))
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		require.Equal(t, unhex(test.mac), mac.CMAC(b, msg[:test.len]))
+	}
+}
+
+func TestPad(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal(unhex("011e800000000000"), mac.Pad(unhex("011e"), 8))
+	require.Equal(unhex("0102030405060708"+"8000000000000000"), mac.Pad(unhex("0102030405060708"), 8))
+
+	msg, err := mac.Unpad(unhex("011e800000000000"))
+	require.NoError(err)
+	require.Equal(unhex("011e"), msg)
+
+	_, err = mac.Unpad(unhex("011e000000000000"))
+	require.ErrorIs(err, mac.ErrInvalidPadding)
+}
@@ -0,0 +1,311 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package key exposes private keys stored on a card as crypto.Signer and crypto.Decrypter.
+package key
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/pin"
+)
+
+var (
+	ErrUnsupportedKey     = errors.New("unsupported key type")
+	ErrUnsupportedOptions = errors.New("unsupported options")
+	ErrDecryption         = errors.New("decryption error")
+	ErrMessageTooLong     = errors.New("message too long for key size")
+
+	errVerify = errors.New("failed to verify")
+)
+
+var (
+	_ crypto.Signer    = (*PrivateKey)(nil)
+	_ crypto.Decrypter = (*PrivateKey)(nil)
+)
+
+// Operator performs the private key operations on the card.
+// This is implemented either by generic commands like PSO
+// or by applet specific commands.
+type Operator interface {
+	// Sign computes a signature over the prepared input.
+	Sign(ctx context.Context, input []byte) ([]byte, error)
+
+	// Decrypt deciphers the ciphertext.
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// PrivateKey is a private key stored on a card.
+//
+// For RSA keys, the input of signature operations is the DigestInfo
+// of PKCS #1 v1.5 signatures or the plain digest of PSS signatures.
+// The card is expected to apply the padding itself unless RawRSA is set.
+// For ECDSA keys, the digest is signed and a raw signature (r || s)
+// returned by the card is converted into its ASN.1 representation.
+// For Ed25519 keys, the message itself is signed.
+type PrivateKey struct {
+	PublicKey crypto.PublicKey
+	Operator  Operator
+
+	// RawRSA indicates that the card performs raw RSA operations.
+	// In this case, padding is applied before signing and
+	// removed after decryption by the adapter.
+	RawRSA bool
+
+	// Verify is called if the card indicates that the security status is not satisfied.
+	// The operation is retried once after the callback returned successfully.
+	// It is intended for prompting for a PIN and verifying it, e.g. via VerifyPIN().
+	Verify func(ctx context.Context) error
+}
+
+// New creates a new private key using the operator for the private key operations.
+func New(pub crypto.PublicKey, op Operator) *PrivateKey {
+	return &PrivateKey{
+		PublicKey: pub,
+		Operator:  op,
+	}
+}
+
+// VerifyPIN returns a callback for PrivateKey.Verify which
+// prompts for a PIN and verifies it using the reference.
+// The PIN returned by the prompt is zeroed after its verification.
+func VerifyPIN(ref *pin.Reference, prompt func() ([]byte, error)) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		p, err := prompt()
+		if err != nil {
+			return fmt.Errorf("failed to prompt for PIN: %w", err)
+		}
+
+		defer clear(p)
+
+		return ref.VerifyContext(ctx, p)
+	}
+}
+
+// Public returns the public key corresponding to the private key.
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.PublicKey
+}
+
+// Sign signs the digest with the private key.
+func (k *PrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return k.SignContext(context.Background(), rand, digest, opts)
+}
+
+// SignContext is like Sign but uses the provided context.
+func (k *PrivateKey) SignContext(ctx context.Context, rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	switch pub := k.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return k.signRSA(ctx, rand, pub, digest, opts)
+
+	case *ecdsa.PublicKey:
+		sig, err := k.sign(ctx, digest)
+		if err != nil {
+			return nil, err
+		}
+
+		return marshalECDSASignature(pub, sig)
+
+	case ed25519.PublicKey:
+		if opts.HashFunc() != crypto.Hash(0) {
+			return nil, fmt.Errorf("%w: Ed25519 requires an unhashed message", ErrUnsupportedOptions)
+		}
+
+		return k.sign(ctx, digest)
+
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+	}
+}
+
+func (k *PrivateKey) signRSA(ctx context.Context, rand io.Reader, pub *rsa.PublicKey, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hash := opts.HashFunc()
+	if hash != crypto.Hash(0) && len(digest) != hash.Size() {
+		return nil, fmt.Errorf("%w: digest length does not match hash function", ErrUnsupportedOptions)
+	}
+
+	var (
+		input []byte
+		err   error
+	)
+
+	if pssOpts, ok := opts.(*rsa.PSSOptions); ok {
+		if !k.RawRSA {
+			input = digest
+		} else if input, err = encodePSS(rand, pub, hash, digest, pssOpts.SaltLength); err != nil {
+			return nil, err
+		}
+	} else {
+		if input, err = digestInfo(hash, digest); err != nil {
+			return nil, err
+		}
+
+		if k.RawRSA {
+			if input, err = padPKCS1v15(pub, input); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sig, err := k.sign(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return leftPad(sig, pub.Size()), nil
+}
+
+// Decrypt decrypts the ciphertext with the private key.
+// Only RSA keys with PKCS #1 v1.5 padding are supported.
+// If opts is a *rsa.PKCS1v15DecryptOptions with a non-zero SessionKeyLen,
+// the semantics of rsa.DecryptPKCS1v15SessionKey are followed: a random key
+// of that length is returned instead of an error if the decryption fails.
+func (k *PrivateKey) Decrypt(rand io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return k.DecryptContext(context.Background(), rand, ciphertext, opts)
+}
+
+// DecryptContext is like Decrypt but uses the provided context.
+func (k *PrivateKey) DecryptContext(ctx context.Context, rand io.Reader, ciphertext []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	pub, ok := k.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, k.PublicKey)
+	}
+
+	switch opts := opts.(type) {
+	case nil:
+	case *rsa.PKCS1v15DecryptOptions:
+		if opts.SessionKeyLen > 0 {
+			return k.decryptSessionKey(ctx, rand, pub, ciphertext, opts.SessionKeyLen)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedOptions, opts)
+	}
+
+	pt, err := k.decrypt(ctx, pub, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	if !k.RawRSA {
+		return pt, nil
+	}
+
+	defer clear(pt)
+
+	return unpadPKCS1v15(leftPad(pt, pub.Size()))
+}
+
+// decryptSessionKey decrypts a session key of a fixed length.
+// To avoid padding oracles like Bleichenbacher's attack, a random key is returned
+// if the card rejects the ciphertext or the padding or length of the plaintext is invalid.
+// The checks of the plaintext are performed in constant time.
+// See: rsa.DecryptPKCS1v15SessionKey
+func (k *PrivateKey) decryptSessionKey(ctx context.Context, rand io.Reader, pub *rsa.PublicKey, ciphertext []byte, keyLen int) ([]byte, error) {
+	if pub.Size()-(keyLen+3+8) < 0 {
+		return nil, ErrDecryption
+	}
+
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(rand, key); err != nil {
+		return nil, err
+	}
+
+	pt, err := k.decrypt(ctx, pub, ciphertext)
+	if err != nil {
+		// The card rejected the ciphertext
+		if errors.As(err, new(iso.Code)) && !errors.Is(err, errVerify) && !errors.Is(err, iso.ErrSecurityStatusNotSatisfied) {
+			return key, nil
+		}
+
+		return nil, err
+	}
+
+	defer clear(pt)
+
+	em := leftPad(pt, pub.Size())
+	valid, index := 1, len(em)-len(pt)
+
+	if k.RawRSA {
+		valid, index = checkPKCS1v15(em)
+	}
+
+	valid &= subtle.ConstantTimeEq(int32(len(em)-index), int32(keyLen)) //nolint:gosec
+	subtle.ConstantTimeCopy(valid, key, em[len(em)-keyLen:])
+
+	return key, nil
+}
+
+func (k *PrivateKey) decrypt(ctx context.Context, pub *rsa.PublicKey, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) > pub.Size() {
+		return nil, ErrDecryption
+	}
+
+	return k.retry(ctx, func() ([]byte, error) {
+		return k.Operator.Decrypt(ctx, leftPad(ciphertext, pub.Size()))
+	})
+}
+
+func (k *PrivateKey) sign(ctx context.Context, input []byte) ([]byte, error) {
+	return k.retry(ctx, func() ([]byte, error) {
+		return k.Operator.Sign(ctx, input)
+	})
+}
+
+// retry invokes the Verify callback and retries the operation
+// if the card indicates that the security status is not satisfied.
+func (k *PrivateKey) retry(ctx context.Context, op func() ([]byte, error)) ([]byte, error) {
+	out, err := op()
+	if err == nil || k.Verify == nil || !errors.Is(err, iso.ErrSecurityStatusNotSatisfied) {
+		return out, err
+	}
+
+	if err := k.Verify(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %w", errVerify, err)
+	}
+
+	return op()
+}
+
+// marshalECDSASignature converts a raw ECDSA signature (r || s) into its ASN.1 representation.
+// Signatures which are already encoded in ASN.1 are returned as is.
+func marshalECDSASignature(pub *ecdsa.PublicKey, sig []byte) ([]byte, error) {
+	n := (pub.Curve.Params().BitSize + 7) / 8
+	if len(sig) != 2*n {
+		return sig, nil
+	}
+
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		R: new(big.Int).SetBytes(sig[:n]),
+		S: new(big.Int).SetBytes(sig[n:]),
+	})
+}
+
+// leftPad prepends zeros to b up to a length of n bytes.
+func leftPad(b []byte, n int) []byte {
+	if len(b) >= n {
+		return b
+	}
+
+	p := make([]byte, n)
+	copy(p[n-len(b):], b)
+
+	return p
+}
@@ -0,0 +1,190 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package key_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/key"
+	"cunicu.li/go-iso7816/test"
+)
+
+// rawRSA emulates a card performing raw RSA operations.
+type rawRSA struct {
+	sk       *rsa.PrivateKey
+	verified bool
+}
+
+func (o *rawRSA) Sign(_ context.Context, input []byte) ([]byte, error) {
+	if !o.verified {
+		return nil, iso.ErrSecurityStatusNotSatisfied
+	}
+
+	return new(big.Int).Exp(new(big.Int).SetBytes(input), o.sk.D, o.sk.N).Bytes(), nil
+}
+
+func (o *rawRSA) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return o.Sign(ctx, ciphertext)
+}
+
+// paddingRSA emulates a card applying the PKCS #1 v1.5 padding itself.
+type paddingRSA struct {
+	sk *rsa.PrivateKey
+}
+
+func (o *paddingRSA) Sign(_ context.Context, input []byte) ([]byte, error) {
+	return rsa.SignPKCS1v15(nil, o.sk, crypto.Hash(0), input)
+}
+
+func (o *paddingRSA) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	pt, err := rsa.DecryptPKCS1v15(nil, o.sk, ciphertext)
+	if err != nil {
+		return nil, iso.ErrIncorrectData
+	}
+
+	return pt, nil
+}
+
+func TestRSA(t *testing.T) {
+	require := require.New(t)
+
+	sk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+
+	digest := sha256.Sum256([]byte("hello"))
+
+	raw := &rawRSA{sk: sk}
+	rawKey := key.New(&sk.PublicKey, raw)
+	rawKey.RawRSA = true
+	rawKey.Verify = func(context.Context) error {
+		raw.verified = true
+		return nil
+	}
+
+	for _, k := range []*key.PrivateKey{
+		rawKey,
+		key.New(&sk.PublicKey, &paddingRSA{sk: sk}),
+	} {
+		sig, err := k.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(err)
+
+		err = rsa.VerifyPKCS1v15(&sk.PublicKey, crypto.SHA256, digest[:], sig)
+		require.NoError(err)
+
+		ct, err := rsa.EncryptPKCS1v15(rand.Reader, &sk.PublicKey, []byte("secret"))
+		require.NoError(err)
+
+		pt, err := k.Decrypt(rand.Reader, ct, nil)
+		require.NoError(err)
+		require.Equal([]byte("secret"), pt)
+	}
+
+	for _, saltLength := range []int{rsa.PSSSaltLengthAuto, rsa.PSSSaltLengthEqualsHash} {
+		opts := &rsa.PSSOptions{
+			SaltLength: saltLength,
+			Hash:       crypto.SHA256,
+		}
+
+		sig, err := rawKey.Sign(rand.Reader, digest[:], opts)
+		require.NoError(err)
+
+		err = rsa.VerifyPSS(&sk.PublicKey, crypto.SHA256, digest[:], sig, opts)
+		require.NoError(err)
+	}
+
+	_, err = rawKey.Decrypt(rand.Reader, []byte{0x01}, &rsa.OAEPOptions{})
+	require.ErrorIs(err, key.ErrUnsupportedOptions)
+}
+
+func TestRSASessionKey(t *testing.T) {
+	require := require.New(t)
+
+	sk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+
+	rawKey := key.New(&sk.PublicKey, &rawRSA{sk: sk, verified: true})
+	rawKey.RawRSA = true
+
+	secret := []byte("0123456789abcdef")
+	opts := &rsa.PKCS1v15DecryptOptions{
+		SessionKeyLen: len(secret),
+	}
+
+	ct, err := rsa.EncryptPKCS1v15(rand.Reader, &sk.PublicKey, secret)
+	require.NoError(err)
+
+	ctShort, err := rsa.EncryptPKCS1v15(rand.Reader, &sk.PublicKey, secret[:8])
+	require.NoError(err)
+
+	// Block type 1 instead of 2
+	em := make([]byte, sk.Size())
+	em[1] = 0x01
+	for i := 2; i < len(em)-len(secret)-1; i++ {
+		em[i] = 0xff
+	}
+	copy(em[len(em)-len(secret):], secret)
+	ctMalformed := new(big.Int).Exp(new(big.Int).SetBytes(em), big.NewInt(int64(sk.E)), sk.N).Bytes()
+
+	for _, k := range []*key.PrivateKey{
+		rawKey,
+		key.New(&sk.PublicKey, &paddingRSA{sk: sk}),
+	} {
+		pt, err := k.Decrypt(rand.Reader, ct, opts)
+		require.NoError(err)
+		require.Equal(secret, pt)
+
+		// Invalid padding and lengths must not be distinguishable
+		for _, ct := range [][]byte{ctMalformed, ctShort} {
+			pt, err := k.Decrypt(rand.Reader, ct, opts)
+			require.NoError(err)
+			require.Len(pt, len(secret))
+			require.NotEqual(secret, pt)
+		}
+
+		_, err = k.Decrypt(rand.Reader, ctMalformed, nil)
+		require.Error(err)
+	}
+}
+
+func TestECDSA(t *testing.T) {
+	require := require.New(t)
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	k := key.New(&ecdsa.PublicKey{Curve: elliptic.P256()}, &key.PSO{
+		Card: iso.NewCard(mockCard),
+		Signature: iso.ControlReferenceTemplate{
+			Tag:        iso.TagCRTDigitalSignature,
+			PrivateKey: []byte{0x9C},
+		},
+	})
+
+	sig, err := k.Sign(rand.Reader, make([]byte, 32), crypto.SHA256)
+	require.NoError(err)
+
+	var rs struct {
+		R, S *big.Int
+	}
+
+	_, err = asn1.Unmarshal(sig, &rs)
+	require.NoError(err)
+	require.Equal(int64(1), rs.R.Int64())
+	require.Equal(int64(2), rs.S.Int64())
+
+	err = mockCard.Close()
+	require.NoError(err)
+}
@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package key
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+// DER encoded prefixes of the DigestInfo structure
+// See: RFC 8017 Section 9.2 EMSA-PKCS1-v1_5
+//
+//nolint:gochecknoglobals
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.MD5:     {0x30, 0x20, 0x30, 0x0c, 0x06, 0x08, 0x2a, 0x86, 0x48, 0x86, 0xf7, 0x0d, 0x02, 0x05, 0x05, 0x00, 0x04, 0x10},
+	crypto.SHA1:    {0x30, 0x21, 0x30, 0x09, 0x06, 0x05, 0x2b, 0x0e, 0x03, 0x02, 0x1a, 0x05, 0x00, 0x04, 0x14},
+	crypto.SHA224:  {0x30, 0x2d, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x04, 0x05, 0x00, 0x04, 0x1c},
+	crypto.SHA256:  {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384:  {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512:  {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+	crypto.MD5SHA1: {}, // Used by TLS 1.0 and 1.1 without prefix
+}
+
+// digestInfo prefixes the digest by the DigestInfo structure of the hash function.
+// A hash function of zero signs the digest as is.
+func digestInfo(hash crypto.Hash, digest []byte) ([]byte, error) {
+	if hash == crypto.Hash(0) {
+		return digest, nil
+	}
+
+	prefix, ok := digestInfoPrefixes[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported hash function %s", ErrUnsupportedOptions, hash)
+	}
+
+	return append(bytes.Clone(prefix), digest...), nil
+}
+
+// padPKCS1v15 applies the PKCS #1 v1.5 signature padding (block type 1).
+// See: RFC 8017 Section 9.2 EMSA-PKCS1-v1_5
+func padPKCS1v15(pub *rsa.PublicKey, t []byte) ([]byte, error) {
+	k := pub.Size()
+	if len(t)+11 > k {
+		return nil, ErrMessageTooLong
+	}
+
+	em := make([]byte, k)
+	em[1] = 0x01
+
+	for i := 2; i < k-len(t)-1; i++ {
+		em[i] = 0xFF
+	}
+
+	copy(em[k-len(t):], t)
+
+	return em, nil
+}
+
+// unpadPKCS1v15 removes the PKCS #1 v1.5 encryption padding (block type 2).
+// See: RFC 8017 Section 7.2.2 RSAES-PKCS1-v1_5 decryption operation
+func unpadPKCS1v15(em []byte) ([]byte, error) {
+	valid, index := checkPKCS1v15(em)
+	if valid != 1 {
+		return nil, ErrDecryption
+	}
+
+	return bytes.Clone(em[index:]), nil
+}
+
+// checkPKCS1v15 checks the PKCS #1 v1.5 encryption padding in constant time.
+// It returns 1 if the padding is valid and the index of the message in em.
+func checkPKCS1v15(em []byte) (valid, index int) {
+	if len(em) < 11 {
+		return 0, 0
+	}
+
+	valid = subtle.ConstantTimeByteEq(em[0], 0x00) & subtle.ConstantTimeByteEq(em[1], 0x02)
+
+	// Find the first zero byte after the padding string in constant time
+	lookingForIndex := 1
+	for i := 2; i < len(em); i++ {
+		isZero := subtle.ConstantTimeByteEq(em[i], 0x00)
+		index = subtle.ConstantTimeSelect(lookingForIndex&isZero, i, index)
+		lookingForIndex = subtle.ConstantTimeSelect(isZero, 0, lookingForIndex)
+	}
+
+	// The padding string must be at least eight bytes long
+	valid &= subtle.ConstantTimeLessOrEq(2+8, index)
+	valid &= 1 - lookingForIndex
+
+	return valid, index + 1
+}
+
+// encodePSS applies the EMSA-PSS encoding to a digest and returns
+// an encoded message with the length of the modulus.
+// See: RFC 8017 Section 9.1.1 EMSA-PSS encoding operation
+func encodePSS(rand io.Reader, pub *rsa.PublicKey, hash crypto.Hash, digest []byte, saltLength int) ([]byte, error) {
+	if !hash.Available() {
+		return nil, fmt.Errorf("%w: unsupported hash function %s", ErrUnsupportedOptions, hash)
+	}
+
+	h := hash.New()
+	hLen := h.Size()
+
+	emBits := pub.N.BitLen() - 1
+	emLen := (emBits + 7) / 8
+
+	switch saltLength {
+	case rsa.PSSSaltLengthAuto:
+		saltLength = emLen - hLen - 2
+	case rsa.PSSSaltLengthEqualsHash:
+		saltLength = hLen
+	}
+
+	if saltLength < 0 || emLen < hLen+saltLength+2 {
+		return nil, ErrMessageTooLong
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+
+	// H = Hash(00 00 00 00 00 00 00 00 || mHash || salt)
+	h.Write(make([]byte, 8))
+	h.Write(digest)
+	h.Write(salt)
+	mh := h.Sum(nil)
+
+	// DB = PS || 01 || salt
+	db := make([]byte, emLen-hLen-1)
+	db[len(db)-saltLength-1] = 0x01
+	copy(db[len(db)-saltLength:], salt)
+
+	mgf1XOR(db, hash.New(), mh)
+
+	// Clear the leftmost bits which exceed emBits
+	db[0] &= 0xFF >> (8*emLen - emBits)
+
+	em := make([]byte, 0, pub.Size())
+	em = append(em, db...)
+	em = append(em, mh...)
+	em = append(em, 0xBC)
+
+	return leftPad(em, pub.Size()), nil
+}
+
+// mgf1XOR XORs out with the output of the mask generation function MGF1.
+// See: RFC 8017 Appendix B.2.1 MGF1
+func mgf1XOR(out []byte, h hash.Hash, seed []byte) {
+	var counter [4]byte
+
+	for done := 0; done < len(out); {
+		h.Reset()
+		h.Write(seed)
+		h.Write(counter[:])
+		digest := h.Sum(nil)
+
+		for i := 0; i < len(digest) && done < len(out); i++ {
+			out[done] ^= digest[i]
+			done++
+		}
+
+		binary.BigEndian.PutUint32(counter[:], binary.BigEndian.Uint32(counter[:])+1)
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package key
+
+import (
+	"context"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+// paddingIndicatorNone is the padding-content indicator byte
+// prepended to RSA cryptograms passed to DECIPHER.
+const paddingIndicatorNone byte = 0x00
+
+var _ Operator = (*PSO)(nil)
+
+// PSO performs private key operations using the PERFORM SECURITY OPERATION command.
+// If the tag of the signature or confidentiality template is set,
+// it is set in the current security environment before each operation.
+// See: ISO 7816-8
+type PSO struct {
+	Card *iso.Card
+
+	// Signature is the digital signature template (DST) used for signing.
+	Signature iso.ControlReferenceTemplate
+
+	// Confidentiality is the confidentiality template (CT) used for decryption.
+	Confidentiality iso.ControlReferenceTemplate
+}
+
+// Sign computes a signature using the COMPUTE DIGITAL SIGNATURE operation.
+func (p *PSO) Sign(ctx context.Context, input []byte) ([]byte, error) {
+	if err := p.setSecurityEnvironment(ctx, p.Signature); err != nil {
+		return nil, err
+	}
+
+	return p.Card.ComputeDigitalSignatureContext(ctx, input)
+}
+
+// Decrypt deciphers the ciphertext using the DECIPHER operation.
+func (p *PSO) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if err := p.setSecurityEnvironment(ctx, p.Confidentiality); err != nil {
+		return nil, err
+	}
+
+	return p.Card.DecipherContext(ctx, append([]byte{paddingIndicatorNone}, ciphertext...))
+}
+
+func (p *PSO) setSecurityEnvironment(ctx context.Context, crt iso.ControlReferenceTemplate) error {
+	if crt.Tag == 0 {
+		return nil
+	}
+
+	if err := p.Card.SetSecurityEnvironmentContext(ctx, iso.SEUsageCompute, crt); err != nil {
+		return fmt.Errorf("failed to set security environment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+const (
+	// lenCmdOverheadExtended is the length of the header, the extended Lc
+	// and extended Le fields of a command APDU of case 4.
+	lenCmdOverheadExtended = LenHeader + 3 + 2
+
+	// lenRespTrailer is the length of the status bytes of a response APDU.
+	lenRespTrailer = 2
+)
+
+// TransferLimits are the limits of the command and response APDUs
+// which have been negotiated with the card.
+type TransferLimits struct {
+	// ExtendedLength indicates that the card supports extended Lc and Le fields.
+	ExtendedLength bool
+
+	// CommandChaining indicates that the card supports command chaining.
+	CommandChaining bool
+
+	// MaxLenCmdData is the maximum length of the command data field (Nc)
+	// of a single command APDU. Larger data fields are sent as a command chain.
+	MaxLenCmdData int
+
+	// MaxLenRespData is the maximum length of the response data field (Ne)
+	// requested by a single command APDU. Larger responses are fetched
+	// in chunks with GET RESPONSE.
+	MaxLenRespData int
+}
+
+// Limits returns the transfer limits used by Send.
+// Unless Negotiate() has been called, the limits of standard length APDUs
+// or those configured via MaxLenCmdData and MaxLenRespData are returned.
+func (c *Card) Limits() TransferLimits {
+	if c.limits != nil {
+		return *c.limits
+	}
+
+	return TransferLimits{
+		ExtendedLength:  c.maxLenCmdData() > MaxLenCmdDataStandard || c.maxLenRespData() > MaxLenRespDataStandard,
+		CommandChaining: true,
+		MaxLenCmdData:   c.maxLenCmdData(),
+		MaxLenRespData:  c.maxLenRespData(),
+	}
+}
+
+// Negotiate determines the transfer limits of the card and
+// configures MaxLenCmdData and MaxLenRespData accordingly.
+//
+// The limits are taken from the card capabilities of the historical bytes
+// and the extended length information of EF.ATR/INFO if indicated.
+// Cards without card capabilities are probed by reading EF.ATR/INFO with
+// an extended length command. Standard length APDUs are used if neither
+// method indicates support for extended length fields.
+//
+// The limits are negotiated only once. Subsequent calls return the previous result.
+// Like Send, Negotiate is safe for concurrent use.
+// After negotiation, Send limits the expected response length (Ne) of commands
+// to the negotiated maximum and fetches larger responses in chunks.
+// See: ISO 7816-4 Section 8.1.1.2.7 Card capabilities
+func (c *Card) Negotiate() (TransferLimits, error) {
+	return c.NegotiateContext(context.Background())
+}
+
+// NegotiateContext is like Negotiate but uses the provided context.
+func (c *Card) NegotiateContext(ctx context.Context) (TransferLimits, error) {
+	ctx, release, err := c.acquire(ctx)
+	if err != nil {
+		return TransferLimits{}, err
+	}
+
+	defer release()
+
+	if c.limits != nil {
+		return *c.limits, nil
+	}
+
+	l := TransferLimits{
+		CommandChaining: true,
+		MaxLenCmdData:   MaxLenCmdDataStandard,
+		MaxLenRespData:  MaxLenRespDataStandard,
+	}
+
+	if hb := c.historicalBytes(); hb != nil && hb.CardCapabilities != 0 {
+		if err := c.negotiateFromCapabilities(ctx, &l, hb.CardCapabilities); err != nil {
+			return l, err
+		}
+	} else if err := c.probeExtendedLength(ctx, &l); err != nil {
+		return l, err
+	}
+
+	c.limits = &l
+	c.MaxLenCmdData = l.MaxLenCmdData
+	c.MaxLenRespData = l.MaxLenRespData
+
+	return l, nil
+}
+
+func (c *Card) negotiateFromCapabilities(ctx context.Context, l *TransferLimits, cc CardCapabilities) error {
+	l.CommandChaining = cc&CardCapCommandChaining != 0
+
+	if cc&CardCapExtendedLength == 0 {
+		return nil
+	}
+
+	l.ExtendedLength = true
+	l.MaxLenCmdData = MaxLenCommandDataExtended
+	l.MaxLenRespData = MaxLenResponseDataExtended
+
+	if cc&CardCapExtendedLengthInfoInEFATR == 0 {
+		return nil
+	}
+
+	info, err := c.CardInfoContext(ctx)
+	if err != nil {
+		if isStatusError(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	l.apply(info)
+
+	return nil
+}
+
+// probeExtendedLength reads EF.ATR/INFO with an extended length command.
+// Extended length fields are considered to be supported only if the
+// command succeeds. The extended length information is used if present.
+func (c *Card) probeExtendedLength(ctx context.Context, l *TransferLimits) error {
+	cmd, _ := binaryCommand(InsReadBinary, sfiATR, 0)
+	cmd.Ne = MaxLenResponseDataExtended
+
+	resp, err := c.SendContext(ctx, cmd)
+	if err != nil {
+		var se *StatusError
+
+		switch {
+		case errors.As(err, &se) && se.Code == ErrEOF && len(se.Data) > 0:
+			resp = se.Data
+
+		case se != nil:
+			// Extended length is not supported or EF.ATR/INFO is absent
+			return nil
+
+		default:
+			return fmt.Errorf("failed to probe extended length: %w", err)
+		}
+	}
+
+	l.ExtendedLength = true
+	l.MaxLenCmdData = MaxLenCommandDataExtended
+	l.MaxLenRespData = MaxLenResponseDataExtended
+
+	// Ignore malformed contents as the card already proved its support
+	info, err := parseCardInfo(resp)
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+
+	if info.CardCapabilities != 0 {
+		l.CommandChaining = info.CardCapabilities&CardCapCommandChaining != 0
+	}
+
+	l.apply(info)
+
+	return nil
+}
+
+// apply restricts the limits to the extended length information of EF.ATR/INFO.
+func (l *TransferLimits) apply(info *CardInfo) {
+	if info.MaxLenCmdAPDU > 0 {
+		n := info.MaxLenCmdAPDU - lenCmdOverheadExtended
+		l.MaxLenCmdData = min(max(n, MaxLenCmdDataStandard), MaxLenCommandDataExtended)
+	}
+
+	if info.MaxLenRespAPDU > 0 {
+		n := info.MaxLenRespAPDU - lenRespTrailer
+		l.MaxLenRespData = min(max(n, MaxLenRespDataStandard), MaxLenResponseDataExtended)
+	}
+}
@@ -5,6 +5,7 @@ package iso7816
 
 import (
 	"encoding/binary"
+	"fmt"
 	"log/slog"
 
 	"cunicu.li/go-iso7816/encoding/tlv"
@@ -20,6 +21,7 @@ const (
 	ctagCardIssuer    tlv.Tag = 0x5 // ISO 7816-4 Section 8.1.1.2.5 Card issuer's data
 	ctagPreIssuing    tlv.Tag = 0x6 // ISO 7816-4 Section 8.1.1.2.6 Pre-issuing data
 	ctagCapabilities  tlv.Tag = 0x7 // ISO 7816-4 Section 8.1.1.2.7 Card capabilities
+	ctagStatus        tlv.Tag = 0x8 // ISO 7816-4 Section 8.1.1.3 Status indicator
 )
 
 type CardService byte
@@ -52,6 +54,14 @@ func (cs *CardService) Decode(b []byte) error {
 	return nil
 }
 
+func (cs CardService) encode() []byte {
+	if cs == 0 {
+		return nil
+	}
+
+	return []byte{byte(cs)}
+}
+
 // Card capabilities
 // See 8.1.1.2.7 Card capabilities
 type (
@@ -118,9 +128,20 @@ func (cc *CardCapabilities) Decode(b []byte) error {
 	return nil
 }
 
+func (cc CardCapabilities) encode() []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(cc))[:3]
+
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+
+	return b
+}
+
 // See: ISO-7816-4 - Section 8.1.1 Historical bytes
 type HistoricalBytes struct {
 	CategoryIndicator byte
+	DIRDataReference  byte // 8.1.1 Category indicator '10'
 	LifeCycleStatus   byte // 8.1.1.3 Status indicator
 	ProcessingStatus  Code // 8.1.1.3 Status indicator
 
@@ -134,22 +155,37 @@ type HistoricalBytes struct {
 	CardCapabilities CardCapabilities // 8.1.1.2.7 Card capabilities
 }
 
+// Decode decodes the historical bytes.
+// Absent historical bytes are decoded into a zero value.
 func (h *HistoricalBytes) Decode(b []byte) (err error) {
+	if len(b) == 0 {
+		*h = HistoricalBytes{}
+		return nil
+	}
+
 	h.CategoryIndicator = b[0]
 
 	switch h.CategoryIndicator {
 	case 0x10:
-		// Not supported
+		if len(b) != 2 {
+			return errInvalidLength
+		}
+
+		h.DIRDataReference = b[1]
 
 	case 0x00:
 		lb := len(b)
+		if lb < 4 {
+			return errInvalidLength
+		}
+
 		h.LifeCycleStatus = b[lb-3]
 		h.ProcessingStatus = Code{b[lb-2], b[lb-1]}
 		b = b[:lb-3]
 		fallthrough
 
 	case 0x80:
-		tvs, err := tlv.DecodeCompact(b)
+		tvs, err := tlv.DecodeCompact(b[1:])
 		if err != nil {
 			return err
 		}
@@ -176,6 +212,10 @@ func (h *HistoricalBytes) Decode(b []byte) (err error) {
 				if err := h.CardService.Decode(tv.Value); err != nil {
 					return err
 				}
+			case ctagStatus:
+				if err := h.decodeStatus(tv.Value); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -187,3 +227,76 @@ func (h *HistoricalBytes) Decode(b []byte) (err error) {
 
 	return nil
 }
+
+// Encode encodes the historical bytes according to their category indicator.
+func (h *HistoricalBytes) Encode() ([]byte, error) {
+	b := []byte{h.CategoryIndicator}
+
+	switch h.CategoryIndicator {
+	case 0x10:
+		return append(b, h.DIRDataReference), nil
+
+	case 0x00, 0x80:
+		tvs := []tlv.TagValue{}
+
+		for _, tv := range []tlv.TagValue{
+			{Tag: ctagCountryCode, Value: h.CountryCode},
+			{Tag: ctagIssuerID, Value: h.IssuerID},
+			{Tag: ctagAID, Value: h.AID},
+			{Tag: ctagInitialAccess, Value: h.InitialAccess},
+			{Tag: ctagCardIssuer, Value: h.CardIssuer},
+			{Tag: ctagPreIssuing, Value: h.PreIssuing},
+			{Tag: ctagCardService, Value: h.CardService.encode()},
+			{Tag: ctagCapabilities, Value: h.CardCapabilities.encode()},
+		} {
+			if len(tv.Value) > 0 {
+				tvs = append(tvs, tv)
+			}
+		}
+
+		// The status indicator is mandatory for category 0x00 and
+		// an optional compact TLV data object for category 0x80
+		status := []byte{h.LifeCycleStatus, h.ProcessingStatus[0], h.ProcessingStatus[1]}
+		if h.CategoryIndicator == 0x80 && (h.LifeCycleStatus != 0 || h.ProcessingStatus != Code{}) {
+			tvs = append(tvs, tlv.TagValue{Tag: ctagStatus, Value: status})
+		}
+
+		ctlvs, err := tlv.EncodeCompact(tvs...)
+		if err != nil {
+			return nil, err
+		}
+
+		b = append(b, ctlvs...)
+
+		if h.CategoryIndicator == 0x00 {
+			b = append(b, status...)
+		}
+
+	default:
+		return nil, fmt.Errorf("%w: unsupported category indicator %#02x", ErrInvalidATR, h.CategoryIndicator)
+	}
+
+	if len(b) > maxLenHistoricalBytes {
+		return nil, fmt.Errorf("%w: historical bytes exceed %d bytes", errInvalidLength, maxLenHistoricalBytes)
+	}
+
+	return b, nil
+}
+
+// decodeStatus decodes the status indicator of category 0x80.
+// See: ISO 7816-4 Section 8.1.1.3 Status indicator
+func (h *HistoricalBytes) decodeStatus(b []byte) error {
+	switch len(b) {
+	case 1:
+		h.LifeCycleStatus = b[0]
+	case 2:
+		h.ProcessingStatus = Code{b[0], b[1]}
+	case 3:
+		h.LifeCycleStatus = b[0]
+		h.ProcessingStatus = Code{b[1], b[2]}
+	default:
+		return errInvalidLength
+	}
+
+	return nil
+}
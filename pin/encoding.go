@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package pin
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Encoding encodes a PIN or password into the reference data
+// which is transmitted in the command data field.
+type Encoding interface {
+	Encode(pin []byte) ([]byte, error)
+}
+
+var (
+	_ Encoding = ASCII{}
+	_ Encoding = BCD{}
+	_ Encoding = FormatBlock2{}
+)
+
+// ASCII transmits the PIN as is.
+// If Length is positive, the PIN is padded with Padding to Length bytes.
+type ASCII struct {
+	Length  int
+	Padding byte
+}
+
+func (e ASCII) Encode(pin []byte) ([]byte, error) {
+	if len(pin) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidPIN)
+	}
+
+	if e.Length <= 0 {
+		return bytes.Clone(pin), nil
+	}
+
+	if len(pin) > e.Length {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidPIN, e.Length)
+	}
+
+	b := make([]byte, e.Length)
+	n := copy(b, pin)
+
+	for i := n; i < len(b); i++ {
+		b[i] = e.Padding
+	}
+
+	return b, nil
+}
+
+// BCD packs the decimal digits of the PIN into binary-coded decimal nibbles.
+// An odd number of digits is padded with a nibble of 'F'.
+// If Length is positive, the PIN is padded with 'FF' bytes to Length bytes.
+type BCD struct {
+	Length int
+}
+
+func (e BCD) Encode(pin []byte) ([]byte, error) {
+	if len(pin) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrInvalidPIN)
+	}
+
+	n := (len(pin) + 1) / 2
+	if e.Length > 0 {
+		if n > e.Length {
+			return nil, fmt.Errorf("%w: longer than %d digits", ErrInvalidPIN, 2*e.Length)
+		}
+
+		n = e.Length
+	}
+
+	b := bytes.Repeat([]byte{0xFF}, n)
+
+	if err := packDigits(b, pin); err != nil {
+		clear(b)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// FormatBlock2 encodes the PIN as an ISO 9564-1 format 2 PIN block.
+// The block consists of the control field '2', the number of digits,
+// the digits as nibbles and a fill of 'F' nibbles up to a total of 8 bytes.
+// See: ISO 9564-1 Format 2 PIN block
+type FormatBlock2 struct{}
+
+const (
+	lenFormatBlock2     = 8
+	minDigitsFormat2    = 4
+	maxDigitsFormat2    = 12
+	controlFormatBlock2 = 0x2
+)
+
+func (FormatBlock2) Encode(pin []byte) ([]byte, error) {
+	if len(pin) < minDigitsFormat2 || len(pin) > maxDigitsFormat2 {
+		return nil, fmt.Errorf("%w: must have %d to %d digits", ErrInvalidPIN, minDigitsFormat2, maxDigitsFormat2)
+	}
+
+	b := bytes.Repeat([]byte{0xFF}, lenFormatBlock2)
+	b[0] = controlFormatBlock2<<4 | byte(len(pin))
+
+	if err := packDigits(b[1:], pin); err != nil {
+		clear(b)
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// packDigits packs the decimal digits of pin into the nibbles of b.
+func packDigits(b, pin []byte) error {
+	for i, d := range pin {
+		if d < '0' || d > '9' {
+			return fmt.Errorf("%w: contains non-decimal digits", ErrInvalidPIN)
+		}
+
+		if i%2 == 0 {
+			b[i/2] = (d-'0')<<4 | b[i/2]&0x0F
+		} else {
+			b[i/2] = b[i/2]&0xF0 | (d - '0')
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,253 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pin implements the management of PINs and passwords
+// using the security related commands of ISO 7816-4 Section 7.5.
+package pin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+var (
+	ErrInvalidPIN     = errors.New("invalid PIN")
+	ErrTooFewRetries  = errors.New("too few retries left")
+	ErrUnknownRetries = errors.New("unknown number of retries left")
+)
+
+// Parameter P1 of RESET RETRY COUNTER
+// See: ISO 7816-4 Section 7.5.10 RESET RETRY COUNTER command
+const (
+	p1ResetCodeAndNew = 0x00 // Command data field contains resetting code and new reference data
+	p1ResetCodeOnly   = 0x01 // Command data field contains resetting code only
+	p1NewOnly         = 0x02 // Command data field contains new reference data only
+	p1Empty           = 0x03 // Command data field is absent
+)
+
+// Status is the verification status of a reference data.
+type Status struct {
+	// Verified indicates that the reference data has already been
+	// verified or that no verification is required.
+	Verified bool
+
+	// RetriesLeft is the number of remaining retries
+	// or -1 if the card did not indicate it.
+	RetriesLeft int
+}
+
+// Reference references a PIN or password stored on the card.
+type Reference struct {
+	Card *iso.Card
+
+	// ID is the reference data qualifier encoded in P2.
+	// Bit 8 distinguishes between global (0) and specific (1) reference data.
+	// See: ISO 7816-4 Section 7.5.6 VERIFY command
+	ID byte
+
+	// Encoding encodes PINs into the command data field.
+	Encoding Encoding
+
+	// ReservedRetries is a safeguard against blocking the reference data.
+	// If positive, Verify queries the retry counter before each VERIFY command
+	// and refuses to send it if only ReservedRetries or less retries are left.
+	ReservedRetries int
+}
+
+// New creates a new reference to a PIN or password identified by the qualifier id.
+func New(card *iso.Card, id byte, enc Encoding) *Reference {
+	return &Reference{
+		Card:     card,
+		ID:       id,
+		Encoding: enc,
+	}
+}
+
+// Status queries the verification status and number of remaining retries
+// by sending a VERIFY command with an absent command data field.
+// See: ISO 7816-4 Section 7.5.6 VERIFY command
+func (r *Reference) Status() (Status, error) {
+	return r.StatusContext(context.Background())
+}
+
+// StatusContext is like Status but uses the provided context.
+func (r *Reference) StatusContext(ctx context.Context) (Status, error) {
+	_, err := r.Card.SendContext(ctx, &iso.CAPDU{
+		Ins: iso.InsVerify,
+		P1:  0x00,
+		P2:  r.ID,
+	})
+	if err == nil {
+		return Status{Verified: true, RetriesLeft: -1}, nil
+	}
+
+	var se *iso.StatusError
+	if errors.As(err, &se) {
+		if retries, ok := se.RetriesLeft(); ok {
+			return Status{RetriesLeft: retries}, nil
+		} else if se.Code == iso.ErrAuthenticationMethodBlocked {
+			return Status{RetriesLeft: 0}, nil
+		}
+	}
+
+	return Status{}, err
+}
+
+// Verify compares the PIN against the reference data stored on the card.
+// On failure, the returned *iso.StatusError indicates the number of remaining retries.
+// If ReservedRetries is positive, the VERIFY command is not sent if the number of
+// remaining retries falls below the safeguard or can not be determined. The retry
+// counter and VERIFY command are exchanged within a single transaction.
+// Reference data which has already been verified is verified again, as cards
+// usually do not indicate the number of remaining retries in this state.
+// See: ISO 7816-4 Section 7.5.6 VERIFY command
+func (r *Reference) Verify(pin []byte) error {
+	return r.VerifyContext(context.Background(), pin)
+}
+
+// VerifyContext is like Verify but uses the provided context.
+func (r *Reference) VerifyContext(ctx context.Context, pin []byte) error {
+	if r.ReservedRetries <= 0 {
+		return r.send(ctx, iso.InsVerify, 0x00, pin)
+	}
+
+	tx, err := r.Card.NewTransactionContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer tx.Close()
+
+	txRef := *r
+	txRef.Card = tx.Card
+
+	sts, err := txRef.StatusContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to query retry counter: %w", err)
+	}
+
+	switch {
+	case sts.Verified:
+	case sts.RetriesLeft < 0:
+		return ErrUnknownRetries
+	case sts.RetriesLeft <= r.ReservedRetries:
+		return fmt.Errorf("%w: %d", ErrTooFewRetries, sts.RetriesLeft)
+	}
+
+	return txRef.send(ctx, iso.InsVerify, 0x00, pin)
+}
+
+// Change replaces the reference data by newPIN after verifying oldPIN.
+// If oldPIN is nil, only the new reference data is sent.
+// See: ISO 7816-4 Section 7.5.7 CHANGE REFERENCE DATA command
+func (r *Reference) Change(oldPIN, newPIN []byte) error {
+	return r.ChangeContext(context.Background(), oldPIN, newPIN)
+}
+
+// ChangeContext is like Change but uses the provided context.
+func (r *Reference) ChangeContext(ctx context.Context, oldPIN, newPIN []byte) error {
+	if oldPIN == nil {
+		return r.send(ctx, iso.InsChangeReferenceData, 0x01, newPIN)
+	}
+
+	return r.send(ctx, iso.InsChangeReferenceData, 0x00, oldPIN, newPIN)
+}
+
+// ResetRetryCounter resets the retry counter of the reference data.
+// The resetting code (e.g. a PUK) and the new reference data are optional
+// and omitted from the command data field if nil.
+// See: ISO 7816-4 Section 7.5.10 RESET RETRY COUNTER command
+func (r *Reference) ResetRetryCounter(puk, newPIN []byte) error {
+	return r.ResetRetryCounterContext(context.Background(), puk, newPIN)
+}
+
+// ResetRetryCounterContext is like ResetRetryCounter but uses the provided context.
+func (r *Reference) ResetRetryCounterContext(ctx context.Context, puk, newPIN []byte) error {
+	switch {
+	case puk != nil && newPIN != nil:
+		return r.send(ctx, iso.InsResetRetryCounter, p1ResetCodeAndNew, puk, newPIN)
+	case puk != nil:
+		return r.send(ctx, iso.InsResetRetryCounter, p1ResetCodeOnly, puk)
+	case newPIN != nil:
+		return r.send(ctx, iso.InsResetRetryCounter, p1NewOnly, newPIN)
+	default:
+		return r.send(ctx, iso.InsResetRetryCounter, p1Empty)
+	}
+}
+
+// Enable switches the verification requirement on.
+// If pin is nil, the command data field is absent.
+// See: ISO 7816-4 Section 7.5.8 ENABLE VERIFICATION REQUIREMENT command
+func (r *Reference) Enable(pin []byte) error {
+	return r.EnableContext(context.Background(), pin)
+}
+
+// EnableContext is like Enable but uses the provided context.
+func (r *Reference) EnableContext(ctx context.Context, pin []byte) error {
+	return r.sendOptional(ctx, iso.InsEnableVerificationRequirement, pin)
+}
+
+// Disable switches the verification requirement off.
+// If pin is nil, the command data field is absent.
+// See: ISO 7816-4 Section 7.5.9 DISABLE VERIFICATION REQUIREMENT command
+func (r *Reference) Disable(pin []byte) error {
+	return r.DisableContext(context.Background(), pin)
+}
+
+// DisableContext is like Disable but uses the provided context.
+func (r *Reference) DisableContext(ctx context.Context, pin []byte) error {
+	return r.sendOptional(ctx, iso.InsDisableVerificationRequirement, pin)
+}
+
+// sendOptional sends a command whose P1 indicates the presence
+// of verification data in the command data field.
+func (r *Reference) sendOptional(ctx context.Context, ins iso.Instruction, pin []byte) error {
+	if pin == nil {
+		return r.send(ctx, ins, 0x01)
+	}
+
+	return r.send(ctx, ins, 0x00, pin)
+}
+
+// send encodes the PINs into the command data field and sends the command.
+// The buffers holding the encoded PINs are zeroed before returning.
+// Copies created by the Card while serializing or protecting
+// the command APDU, e.g. via secure messaging, are not.
+func (r *Reference) send(ctx context.Context, ins iso.Instruction, p1 byte, pins ...[]byte) error {
+	encs := make([][]byte, 0, len(pins))
+	defer func() {
+		for _, enc := range encs {
+			clear(enc)
+		}
+	}()
+
+	n := 0
+	for _, pin := range pins {
+		enc, err := r.Encoding.Encode(pin)
+		if err != nil {
+			return err
+		}
+
+		encs = append(encs, enc)
+		n += len(enc)
+	}
+
+	data := make([]byte, 0, n)
+	defer clear(data[:n])
+
+	for _, enc := range encs {
+		data = append(data, enc...)
+	}
+
+	_, err := r.Card.SendContext(ctx, &iso.CAPDU{
+		Ins:  ins,
+		P1:   p1,
+		P2:   r.ID,
+		Data: data,
+	})
+
+	return err
+}
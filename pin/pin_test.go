@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package pin_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/pin"
+	"cunicu.li/go-iso7816/test"
+)
+
+func TestEncoding(t *testing.T) {
+	for n, c := range map[string]struct {
+		enc pin.Encoding
+		pin string
+		exp string
+	}{
+		"ascii":          {pin.ASCII{}, "123456", "313233343536"},
+		"ascii padded":   {pin.ASCII{Length: 8, Padding: 0xFF}, "123456", "313233343536ffff"},
+		"bcd":            {pin.BCD{}, "12345", "12345f"},
+		"bcd padded":     {pin.BCD{Length: 4}, "1234", "1234ffff"},
+		"format 2 block": {pin.FormatBlock2{}, "12345", "2512345fffffffff"},
+	} {
+		t.Run(n, func(t *testing.T) {
+			require := require.New(t)
+
+			b, err := c.enc.Encode([]byte(c.pin))
+			require.NoError(err)
+			require.Equal(c.exp, hex.EncodeToString(b))
+		})
+	}
+
+	for n, c := range map[string]struct {
+		enc pin.Encoding
+		pin string
+	}{
+		"ascii empty":       {pin.ASCII{}, ""},
+		"ascii too long":    {pin.ASCII{Length: 4}, "12345"},
+		"bcd non-digit":     {pin.BCD{}, "12a4"},
+		"bcd too long":      {pin.BCD{Length: 2}, "12345"},
+		"format 2 short":    {pin.FormatBlock2{}, "123"},
+		"format 2 too long": {pin.FormatBlock2{}, "1234567890123"},
+	} {
+		t.Run(n, func(t *testing.T) {
+			_, err := c.enc.Encode([]byte(c.pin))
+			require.ErrorIs(t, err, pin.ErrInvalidPIN)
+		})
+	}
+}
+
+func TestReference(t *testing.T) {
+	require := require.New(t)
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	ref := pin.New(iso.NewCard(mockCard), 0x81, pin.ASCII{Length: 8, Padding: 0xFF})
+	ref.ReservedRetries = 1
+
+	sts, err := ref.Status()
+	require.NoError(err)
+	require.Equal(pin.Status{RetriesLeft: 3}, sts)
+
+	err = ref.Verify([]byte("123456"))
+	require.NoError(err)
+
+	err = ref.Verify([]byte("000000"))
+	require.ErrorIs(err, iso.StatusClass(0x63))
+
+	var se *iso.StatusError
+	require.ErrorAs(err, &se)
+
+	retries, ok := se.RetriesLeft()
+	require.True(ok)
+	require.Equal(1, retries)
+
+	// The safeguard prevents sending another VERIFY command
+	err = ref.Verify([]byte("000000"))
+	require.ErrorIs(err, pin.ErrTooFewRetries)
+
+	err = ref.ResetRetryCounter([]byte("12345678"), []byte("654321"))
+	require.NoError(err)
+
+	err = ref.Change([]byte("654321"), []byte("111111"))
+	require.NoError(err)
+
+	err = ref.Disable(nil)
+	require.NoError(err)
+
+	sts, err = ref.Status()
+	require.NoError(err)
+	require.Equal(pin.Status{Verified: true, RetriesLeft: -1}, sts)
+
+	// Verified reference data is verified again despite the unknown retry counter
+	err = ref.Verify([]byte("111111"))
+	require.NoError(err)
+
+	err = mockCard.Close()
+	require.NoError(err)
+}
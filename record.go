@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrRecordNumberNotSupported is returned by ReadRecords if the card capabilities
+	// indicate that records can not be referenced by their number.
+	ErrRecordNumberNotSupported = errors.New("record number not supported by card")
+
+	// ErrInvalidSFI is returned if a short EF identifier is outside the range 1-30
+	// and thus can not be encoded in P2 of record commands.
+	ErrInvalidSFI = errors.New("invalid short EF identifier")
+)
+
+// RecordSelector selects how P1 references a record.
+// It is encoded in bits 3-1 of P2 of record commands.
+// See: ISO 7816-4 Section 7.3.2 Table 48
+type RecordSelector byte
+
+const (
+	RecordFirstOccurrence    RecordSelector = 0x00 // First occurrence of the record with the identifier in P1
+	RecordLastOccurrence     RecordSelector = 0x01 // Last occurrence of the record with the identifier in P1
+	RecordNextOccurrence     RecordSelector = 0x02 // Next occurrence of the record with the identifier in P1
+	RecordPreviousOccurrence RecordSelector = 0x03 // Previous occurrence of the record with the identifier in P1
+	RecordNumber             RecordSelector = 0x04 // Record number in P1
+)
+
+const (
+	recordsFromNumber = 0x05 // Records from number in P1 up to the last record
+	searchForward     = 0x04 // Simple search starting at the record number in P1
+
+	maxSFI = 30 // The value 31 is reserved in P2 of record commands
+)
+
+// RecordRef references a record of a record-oriented EF.
+type RecordRef struct {
+	// SFI is the short EF identifier of the EF or zero for the current EF.
+	SFI byte
+
+	// Selector selects whether Value is a record number or identifier.
+	Selector RecordSelector
+
+	// Value is the record number or identifier.
+	Value byte
+}
+
+// RecordByNumber references a record by its number.
+// This requires that the card supports CardCapRecordNumber.
+func RecordByNumber(sfi, number byte) RecordRef {
+	return RecordRef{
+		SFI:      sfi,
+		Selector: RecordNumber,
+		Value:    number,
+	}
+}
+
+// RecordByID references a record by its identifier.
+// This requires that the card supports CardCapRecordIdentifier.
+func RecordByID(sfi, id byte, occurrence RecordSelector) RecordRef {
+	return RecordRef{
+		SFI:      sfi,
+		Selector: occurrence,
+		Value:    id,
+	}
+}
+
+func (r RecordRef) p2() (byte, error) {
+	return recordP2(r.SFI, byte(r.Selector)&0x07)
+}
+
+// recordP2 encodes the short EF identifier in bits 8-4 of P2 of record commands.
+// See: ISO 7816-4 Section 7.3.2 Table 48
+func recordP2(sfi, low byte) (byte, error) {
+	if sfi > maxSFI {
+		return 0, fmt.Errorf("%w: %d", ErrInvalidSFI, sfi)
+	}
+
+	return sfi<<3 | low, nil
+}
+
+// ReadRecord reads a single record of a record-oriented EF.
+// See: ISO 7816-4 Section 7.3.3 READ RECORD (S) command
+func (c *Card) ReadRecord(ref RecordRef) ([]byte, error) {
+	return c.ReadRecordContext(context.Background(), ref)
+}
+
+// ReadRecordContext is like ReadRecord but uses the provided context.
+func (c *Card) ReadRecordContext(ctx context.Context, ref RecordRef) ([]byte, error) {
+	p2, err := ref.p2()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.SendContext(ctx, &CAPDU{
+		Ins: InsReadRecord,
+		P1:  ref.Value,
+		P2:  p2,
+		Ne:  c.maxLenRespData(),
+	})
+}
+
+// ReadRecords reads all records of a linear or cyclic EF.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// The records are read by number until the card signals that no further record exists.
+// ErrRecordNumberNotSupported is returned if the card capabilities of the
+// historical bytes do not include CardCapRecordNumber.
+func (c *Card) ReadRecords(sfi byte) ([][]byte, error) {
+	return c.ReadRecordsContext(context.Background(), sfi)
+}
+
+// ReadRecordsContext is like ReadRecords but uses the provided context.
+func (c *Card) ReadRecordsContext(ctx context.Context, sfi byte) (records [][]byte, err error) {
+	if hb := c.historicalBytes(); hb != nil && hb.CardCapabilities != 0 && hb.CardCapabilities&CardCapRecordNumber == 0 {
+		return nil, ErrRecordNumberNotSupported
+	}
+
+	for number := 1; number <= 0xFE; number++ {
+		record, err := c.ReadRecordContext(ctx, RecordByNumber(sfi, byte(number)))
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read record %d: %w", number, err)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// UpdateRecord replaces the content of a record.
+// See: ISO 7816-4 Section 7.3.5 UPDATE RECORD command
+func (c *Card) UpdateRecord(ref RecordRef, data []byte) error {
+	return c.UpdateRecordContext(context.Background(), ref, data)
+}
+
+// UpdateRecordContext is like UpdateRecord but uses the provided context.
+func (c *Card) UpdateRecordContext(ctx context.Context, ref RecordRef, data []byte) error {
+	p2, err := ref.p2()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, &CAPDU{
+		Ins:  InsUpdateRecord,
+		P1:   ref.Value,
+		P2:   p2,
+		Data: data,
+	})
+
+	return err
+}
+
+// WriteRecord writes data to a record.
+// Depending on the card, the data is written once or combined with the
+// existing content by a logical OR or AND operation.
+// See: ISO 7816-4 Section 7.3.4 WRITE RECORD command
+func (c *Card) WriteRecord(ref RecordRef, data []byte) error {
+	return c.WriteRecordContext(context.Background(), ref, data)
+}
+
+// WriteRecordContext is like WriteRecord but uses the provided context.
+func (c *Card) WriteRecordContext(ctx context.Context, ref RecordRef, data []byte) error {
+	p2, err := ref.p2()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, &CAPDU{
+		Ins:  InsWriteRecord,
+		P1:   ref.Value,
+		P2:   p2,
+		Data: data,
+	})
+
+	return err
+}
+
+// AppendRecord appends a new record to a linear EF or
+// replaces the oldest record of a cyclic EF.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// See: ISO 7816-4 Section 7.3.6 APPEND RECORD command
+func (c *Card) AppendRecord(sfi byte, data []byte) error {
+	return c.AppendRecordContext(context.Background(), sfi, data)
+}
+
+// AppendRecordContext is like AppendRecord but uses the provided context.
+func (c *Card) AppendRecordContext(ctx context.Context, sfi byte, data []byte) error {
+	p2, err := recordP2(sfi, 0x00)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, &CAPDU{
+		Ins:  InsAppendRecord,
+		P1:   0x00,
+		P2:   p2,
+		Data: data,
+	})
+
+	return err
+}
+
+// EraseRecord erases the record with the given number.
+// If all is true, all records starting from the given number up to the last one are erased.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// See: ISO 7816-4 Section 7.3.8 ERASE RECORD (S) command
+func (c *Card) EraseRecord(sfi, number byte, all bool) error {
+	return c.EraseRecordContext(context.Background(), sfi, number, all)
+}
+
+// EraseRecordContext is like EraseRecord but uses the provided context.
+func (c *Card) EraseRecordContext(ctx context.Context, sfi, number byte, all bool) error {
+	ref := RecordByNumber(sfi, number)
+	if all {
+		ref.Selector = recordsFromNumber
+	}
+
+	p2, err := ref.p2()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, &CAPDU{
+		Ins: InsEraseRecord,
+		P1:  ref.Value,
+		P2:  p2,
+	})
+
+	return err
+}
+
+// SearchRecord performs a simple search for records containing pattern,
+// starting at the record with the given number.
+// The EF is referenced by its short EF identifier sfi or the current EF if sfi is zero.
+// It returns the numbers of all matching records.
+// See: ISO 7816-4 Section 7.3.7 SEARCH RECORD command
+func (c *Card) SearchRecord(sfi, number byte, pattern []byte) ([]byte, error) {
+	return c.SearchRecordContext(context.Background(), sfi, number, pattern)
+}
+
+// SearchRecordContext is like SearchRecord but uses the provided context.
+func (c *Card) SearchRecordContext(ctx context.Context, sfi, number byte, pattern []byte) ([]byte, error) {
+	p2, err := recordP2(sfi, searchForward)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers, err := c.SendContext(ctx, &CAPDU{
+		Ins:  InsSearchRecord,
+		P1:   number,
+		P2:   p2,
+		Data: pattern,
+		Ne:   MaxLenRespDataStandard,
+	})
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,290 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+
+	"cunicu.li/go-iso7816/encoding/tlv"
+)
+
+// Control reference templates
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+const (
+	TagCRTAuthentication        tlv.Tag = 0xA4 // Authentication (AT)
+	TagCRTHash                  tlv.Tag = 0xAA // Hash-code (HT)
+	TagCRTCryptographicChecksum tlv.Tag = 0xB4 // Cryptographic checksum (CCT)
+	TagCRTDigitalSignature      tlv.Tag = 0xB6 // Digital signature (DST)
+	TagCRTConfidentiality       tlv.Tag = 0xB8 // Confidentiality (CT)
+)
+
+// Data objects of control reference templates
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+const (
+	TagCRTAlgorithm           tlv.Tag = 0x80 // Cryptographic mechanism reference
+	TagCRTFileReference       tlv.Tag = 0x81 // File identifier or path
+	TagCRTDFName              tlv.Tag = 0x82 // DF name
+	TagCRTPublicKeyReference  tlv.Tag = 0x83 // Reference of a secret or public key
+	TagCRTPrivateKeyReference tlv.Tag = 0x84 // Reference of a session or private key
+	TagCRTUsageQualifier      tlv.Tag = 0x95 // Usage qualifier
+)
+
+// Data objects of PERFORM SECURITY OPERATION
+// See: ISO 7816-8 PERFORM SECURITY OPERATION command
+const (
+	tagPSOPlainValue            tlv.Tag = 0x80 // Plain value not encoded in BER-TLV
+	tagPSOPaddedCryptogram      tlv.Tag = 0x86 // Padding-content indicator byte followed by cryptogram
+	tagPSOHashCode              tlv.Tag = 0x90 // Hash-code
+	tagPSODataToBeSigned        tlv.Tag = 0x9A // Data elements to be signed
+	tagPSODigitalSignature      tlv.Tag = 0x9E // Digital signature
+	tagPSODigitalSignatureInput tlv.Tag = 0xA8 // Data objects for verifying a digital signature
+	tagPSOCertificate           tlv.Tag = 0xBE // Certificate encoded in BER-TLV
+)
+
+// SecurityEnvironmentUsage selects the operations for which
+// MANAGE SECURITY ENVIRONMENT SET configures a control reference template.
+// It is encoded in bits 8-5 of P1.
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+type SecurityEnvironmentUsage byte
+
+const (
+	SEUsageSMCommand  SecurityEnvironmentUsage = 0x10 // Secure messaging in command data field
+	SEUsageSMResponse SecurityEnvironmentUsage = 0x20 // Secure messaging in response data field
+	SEUsageCompute    SecurityEnvironmentUsage = 0x40 // Computation, decipherment, internal authentication and key agreement
+	SEUsageVerify     SecurityEnvironmentUsage = 0x80 // Verification, encipherment, external authentication and key agreement
+)
+
+// Operation of MANAGE SECURITY ENVIRONMENT encoded in P1
+const (
+	mseSet     = 0x01
+	mseStore   = 0xF2
+	mseRestore = 0xF3
+	mseErase   = 0xF4
+)
+
+// ControlReferenceTemplate references the keys and algorithms
+// used by subsequent security operations.
+//
+// Nil fields are omitted from the template.
+type ControlReferenceTemplate struct {
+	// Tag is the tag of the template like TagCRTDigitalSignature.
+	Tag tlv.Tag
+
+	// Algorithm is the reference of the cryptographic mechanism.
+	Algorithm []byte
+
+	// PublicKey is the reference of a secret or public key for direct use.
+	PublicKey []byte
+
+	// PrivateKey is the reference of a private key or of a secret key
+	// for computing a session key.
+	PrivateKey []byte
+
+	// Other contains further data objects like file references or usage qualifiers.
+	Other tlv.TagValues
+}
+
+// Bytes encodes the data objects of the template without the enclosing template tag.
+func (t ControlReferenceTemplate) Bytes() ([]byte, error) {
+	var tvs tlv.TagValues
+
+	for _, do := range []struct {
+		tag   tlv.Tag
+		value []byte
+	}{
+		{TagCRTAlgorithm, t.Algorithm},
+		{TagCRTPublicKeyReference, t.PublicKey},
+		{TagCRTPrivateKeyReference, t.PrivateKey},
+	} {
+		if do.value != nil {
+			tvs = append(tvs, tlv.TagValue{Tag: do.tag, Value: do.value})
+		}
+	}
+
+	tvs = append(tvs, t.Other...)
+
+	return tlv.EncodeBER(tvs...)
+}
+
+// SetSecurityEnvironment sets the control reference template
+// in the current security environment for the given usage.
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+func (c *Card) SetSecurityEnvironment(usage SecurityEnvironmentUsage, crt ControlReferenceTemplate) error {
+	return c.SetSecurityEnvironmentContext(context.Background(), usage, crt)
+}
+
+// SetSecurityEnvironmentContext is like SetSecurityEnvironment but uses the provided context.
+func (c *Card) SetSecurityEnvironmentContext(ctx context.Context, usage SecurityEnvironmentUsage, crt ControlReferenceTemplate) error {
+	data, err := crt.Bytes()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.SendContext(ctx, &CAPDU{
+		Ins:  InsManageSecurityEnvironment,
+		P1:   byte(usage)&0xF0 | mseSet,
+		P2:   byte(crt.Tag),
+		Data: data,
+	})
+
+	return err
+}
+
+// StoreSecurityEnvironment stores the current security environment
+// under the security environment identifier seid.
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+func (c *Card) StoreSecurityEnvironment(seid byte) error {
+	return c.StoreSecurityEnvironmentContext(context.Background(), seid)
+}
+
+// StoreSecurityEnvironmentContext is like StoreSecurityEnvironment but uses the provided context.
+func (c *Card) StoreSecurityEnvironmentContext(ctx context.Context, seid byte) error {
+	return c.manageSecurityEnvironment(ctx, mseStore, seid)
+}
+
+// RestoreSecurityEnvironment replaces the current security environment
+// by the one stored under the security environment identifier seid.
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+func (c *Card) RestoreSecurityEnvironment(seid byte) error {
+	return c.RestoreSecurityEnvironmentContext(context.Background(), seid)
+}
+
+// RestoreSecurityEnvironmentContext is like RestoreSecurityEnvironment but uses the provided context.
+func (c *Card) RestoreSecurityEnvironmentContext(ctx context.Context, seid byte) error {
+	return c.manageSecurityEnvironment(ctx, mseRestore, seid)
+}
+
+// EraseSecurityEnvironment erases the security environment
+// stored under the security environment identifier seid.
+// See: ISO 7816-4 Section 7.5.11 MANAGE SECURITY ENVIRONMENT command
+func (c *Card) EraseSecurityEnvironment(seid byte) error {
+	return c.EraseSecurityEnvironmentContext(context.Background(), seid)
+}
+
+// EraseSecurityEnvironmentContext is like EraseSecurityEnvironment but uses the provided context.
+func (c *Card) EraseSecurityEnvironmentContext(ctx context.Context, seid byte) error {
+	return c.manageSecurityEnvironment(ctx, mseErase, seid)
+}
+
+func (c *Card) manageSecurityEnvironment(ctx context.Context, op, seid byte) error {
+	_, err := c.SendContext(ctx, &CAPDU{
+		Ins: InsManageSecurityEnvironment,
+		P1:  op,
+		P2:  seid,
+	})
+
+	return err
+}
+
+// ComputeDigitalSignature signs data using the key and algorithm
+// configured in the digital signature template of the current security environment.
+// Depending on the card and algorithm, data is either the hash or the DigestInfo
+// of the message to be signed.
+// See: ISO 7816-8 COMPUTE DIGITAL SIGNATURE operation
+func (c *Card) ComputeDigitalSignature(data []byte) ([]byte, error) {
+	return c.ComputeDigitalSignatureContext(context.Background(), data)
+}
+
+// ComputeDigitalSignatureContext is like ComputeDigitalSignature but uses the provided context.
+func (c *Card) ComputeDigitalSignatureContext(ctx context.Context, data []byte) ([]byte, error) {
+	return c.performSecurityOperation(ctx, tagPSODigitalSignature, tagPSODataToBeSigned, data)
+}
+
+// VerifyDigitalSignature verifies the signature of a hash using the key and algorithm
+// configured in the digital signature template of the current security environment.
+// If hash is nil, the hash-code computed by a previous HASH operation is used.
+// See: ISO 7816-8 VERIFY DIGITAL SIGNATURE operation
+func (c *Card) VerifyDigitalSignature(hash, signature []byte) error {
+	return c.VerifyDigitalSignatureContext(context.Background(), hash, signature)
+}
+
+// VerifyDigitalSignatureContext is like VerifyDigitalSignature but uses the provided context.
+func (c *Card) VerifyDigitalSignatureContext(ctx context.Context, hash, signature []byte) error {
+	var tvs tlv.TagValues
+	if hash != nil {
+		tvs = append(tvs, tlv.New(tagPSOHashCode, hash))
+	}
+
+	tvs = append(tvs, tlv.New(tagPSODigitalSignature, signature))
+
+	data, err := tlv.EncodeBER(tvs...)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.performSecurityOperation(ctx, 0x00, tagPSODigitalSignatureInput, data)
+
+	return err
+}
+
+// Hash computes the hash-code of data using the algorithm
+// configured in the hash-code template of the current security environment.
+// See: ISO 7816-8 HASH operation
+func (c *Card) Hash(data []byte) ([]byte, error) {
+	return c.HashContext(context.Background(), data)
+}
+
+// HashContext is like Hash but uses the provided context.
+func (c *Card) HashContext(ctx context.Context, data []byte) ([]byte, error) {
+	return c.performSecurityOperation(ctx, tagPSOHashCode, tagPSOPlainValue, data)
+}
+
+// Encipher encrypts the plain value using the key and algorithm
+// configured in the confidentiality template of the current security environment.
+// The returned cryptogram is prefixed by the padding-content indicator byte.
+// See: ISO 7816-8 ENCIPHER operation
+func (c *Card) Encipher(plain []byte) ([]byte, error) {
+	return c.EncipherContext(context.Background(), plain)
+}
+
+// EncipherContext is like Encipher but uses the provided context.
+func (c *Card) EncipherContext(ctx context.Context, plain []byte) ([]byte, error) {
+	return c.performSecurityOperation(ctx, tagPSOPaddedCryptogram, tagPSOPlainValue, plain)
+}
+
+// Decipher decrypts the cryptogram using the key and algorithm
+// configured in the confidentiality template of the current security environment.
+// The cryptogram must be prefixed by the padding-content indicator byte
+// as returned by Encipher.
+// See: ISO 7816-8 DECIPHER operation
+func (c *Card) Decipher(cryptogram []byte) ([]byte, error) {
+	return c.DecipherContext(context.Background(), cryptogram)
+}
+
+// DecipherContext is like Decipher but uses the provided context.
+func (c *Card) DecipherContext(ctx context.Context, cryptogram []byte) ([]byte, error) {
+	return c.performSecurityOperation(ctx, tagPSOPlainValue, tagPSOPaddedCryptogram, cryptogram)
+}
+
+// VerifyCertificate verifies a BER-TLV encoded certificate like a card verifiable certificate
+// using the public key configured in the digital signature template of the current security environment.
+// On success, the card may use the public key contained in the certificate for subsequent operations.
+// See: ISO 7816-8 VERIFY CERTIFICATE operation
+func (c *Card) VerifyCertificate(cert []byte) error {
+	return c.VerifyCertificateContext(context.Background(), cert)
+}
+
+// VerifyCertificateContext is like VerifyCertificate but uses the provided context.
+func (c *Card) VerifyCertificateContext(ctx context.Context, cert []byte) error {
+	_, err := c.performSecurityOperation(ctx, 0x00, tagPSOCertificate, cert)
+	return err
+}
+
+// performSecurityOperation sends a PERFORM SECURITY OPERATION command.
+// P1 contains the tag of the data object in the response data field
+// and P2 the tag of the data object in the command data field.
+func (c *Card) performSecurityOperation(ctx context.Context, respTag, cmdTag tlv.Tag, data []byte) ([]byte, error) {
+	cmd := &CAPDU{
+		Ins:  InsPerformSecurityOperation,
+		P1:   byte(respTag),
+		P2:   byte(cmdTag),
+		Data: data,
+	}
+
+	if respTag != 0x00 {
+		cmd.Ne = c.maxLenRespData()
+	}
+
+	return c.SendContext(ctx, cmd)
+}
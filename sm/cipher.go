@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package sm
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des" //nolint:gosec
+	"fmt"
+
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+// CipherSuite implements the cryptographic primitives of a
+// secure messaging session.
+type CipherSuite interface {
+	// BlockSize returns the block size of the cipher which
+	// is also the length of the send sequence counter.
+	BlockSize() int
+
+	// Encrypt encrypts padded data.
+	Encrypt(ssc, data []byte) ([]byte, error)
+
+	// Decrypt decrypts padded data.
+	Decrypt(ssc, data []byte) ([]byte, error)
+
+	// MAC computes the message authentication code over padded data.
+	MAC(data []byte) ([]byte, error)
+}
+
+type desCipherSuite struct {
+	enc    cipher.Block
+	macKey []byte
+}
+
+// NewDESCipherSuite creates a cipher suite using 3DES in CBC mode
+// with a zero IV for encryption and the retail MAC for authentication.
+// See: ICAO Doc 9303 Part 11 Section 9.8.6.1 3DES modes of operation
+func NewDESCipherSuite(encKey, macKey []byte) (CipherSuite, error) {
+	if len(macKey) != 16 {
+		return nil, fmt.Errorf("%w: MAC key must be 16 bytes", ErrInvalidKey)
+	}
+
+	enc, err := NewTripleDES(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &desCipherSuite{
+		enc:    enc,
+		macKey: macKey,
+	}, nil
+}
+
+func (s *desCipherSuite) BlockSize() int {
+	return des.BlockSize
+}
+
+func (s *desCipherSuite) Encrypt(_, data []byte) ([]byte, error) {
+	if len(data)%des.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: data is not padded", ErrInvalidLength)
+	}
+
+	out := make([]byte, len(data))
+	iv := make([]byte, des.BlockSize)
+	cipher.NewCBCEncrypter(s.enc, iv).CryptBlocks(out, data)
+
+	return out, nil
+}
+
+func (s *desCipherSuite) Decrypt(_, data []byte) ([]byte, error) {
+	if len(data)%des.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: cryptogram is not a multiple of the block size", ErrInvalidLength)
+	}
+
+	out := make([]byte, len(data))
+	iv := make([]byte, des.BlockSize)
+	cipher.NewCBCDecrypter(s.enc, iv).CryptBlocks(out, data)
+
+	return out, nil
+}
+
+func (s *desCipherSuite) MAC(data []byte) ([]byte, error) {
+	return mac.Retail(s.macKey, nil, data)
+}
+
+type aesCipherSuite struct {
+	enc cipher.Block
+	mac cipher.Block
+}
+
+// NewAESCipherSuite creates a cipher suite using AES in CBC mode
+// with an encrypted send sequence counter as IV for encryption and
+// AES-CMAC truncated to 8 bytes for authentication.
+// See: ICAO Doc 9303 Part 11 Section 9.8.6.2 AES modes of operation
+func NewAESCipherSuite(encKey, macKey []byte) (CipherSuite, error) {
+	enc, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	mac, err := aes.NewCipher(macKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return &aesCipherSuite{
+		enc: enc,
+		mac: mac,
+	}, nil
+}
+
+func (s *aesCipherSuite) BlockSize() int {
+	return aes.BlockSize
+}
+
+func (s *aesCipherSuite) iv(ssc []byte) []byte {
+	iv := make([]byte, aes.BlockSize)
+	s.enc.Encrypt(iv, ssc)
+	return iv
+}
+
+func (s *aesCipherSuite) Encrypt(ssc, data []byte) ([]byte, error) {
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: data is not padded", ErrInvalidLength)
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(s.enc, s.iv(ssc)).CryptBlocks(out, data)
+
+	return out, nil
+}
+
+func (s *aesCipherSuite) Decrypt(ssc, data []byte) ([]byte, error) {
+	if len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: cryptogram is not a multiple of the block size", ErrInvalidLength)
+	}
+
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(s.enc, s.iv(ssc)).CryptBlocks(out, data)
+
+	return out, nil
+}
+
+func (s *aesCipherSuite) MAC(data []byte) ([]byte, error) {
+	return mac.CMAC(s.mac, data)[:8], nil
+}
+
+// NewTripleDES creates a 3DES block cipher from a double or triple length key.
+// Double length keys are used as K1 || K2 || K1.
+func NewTripleDES(key []byte) (cipher.Block, error) {
+	var k []byte
+
+	switch len(key) {
+	case 16:
+		k = make([]byte, 0, 24)
+		k = append(k, key...)
+		k = append(k, key[:8]...)
+	case 24:
+		k = key
+	default:
+		return nil, fmt.Errorf("%w: 3DES key must be 16 or 24 bytes", ErrInvalidKey)
+	}
+
+	b, err := des.NewTripleDESCipher(k) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return b, nil
+}
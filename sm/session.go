@@ -0,0 +1,264 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package sm
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+	"cunicu.li/go-iso7816/internal/mac"
+)
+
+// Secure messaging data objects
+// See: ISO 7816-4 Section 10.2 Basic SM data objects
+const (
+	TagPlainValue            tlv.Tag = 0x81 // Plain value not encoded in BER-TLV
+	TagCryptogram            tlv.Tag = 0x85 // Cryptogram (plain value encoded in BER-TLV)
+	TagPaddedCryptogram      tlv.Tag = 0x87 // Padding-content indicator byte followed by cryptogram
+	TagCryptographicChecksum tlv.Tag = 0x8E // Cryptographic checksum (MAC)
+	TagLe                    tlv.Tag = 0x97 // One or two bytes encoding Le in the unsecured command APDU
+	TagProcessingStatus      tlv.Tag = 0x99 // Processing status (SW1-SW2)
+)
+
+// paddingIndicatorISO9797M2 is the padding-content indicator for ISO 9797-1 padding method 2.
+const paddingIndicatorISO9797M2 byte = 0x01
+
+var _ Wrapper = (*Session)(nil)
+
+// Session implements secure messaging according to ISO 7816-4 Section 10
+// as used for example by ICAO 9303 eMRTDs and the German eID.
+//
+// Command data is encrypted into DO'87' (even INS) or DO'85' (odd INS),
+// the expected length is transported in DO'97' and a MAC over the send
+// sequence counter, the command header and all data objects is added as DO'8E'.
+type Session struct {
+	suite CipherSuite
+	ssc   []byte
+}
+
+// NewSession creates a new secure messaging session using the provided cipher suite
+// and initial send sequence counter (SSC).
+// If ssc is nil, the SSC is initialized with zeros.
+func NewSession(suite CipherSuite, ssc []byte) (*Session, error) {
+	if ssc == nil {
+		ssc = make([]byte, suite.BlockSize())
+	} else if len(ssc) != suite.BlockSize() {
+		return nil, fmt.Errorf("%w: send sequence counter must have a length of %d bytes", ErrInvalidLength, suite.BlockSize())
+	}
+
+	return &Session{
+		suite: suite,
+		ssc:   append([]byte{}, ssc...),
+	}, nil
+}
+
+// SendSequenceCounter returns a copy of the current send sequence counter.
+func (s *Session) SendSequenceCounter() []byte {
+	return append([]byte{}, s.ssc...)
+}
+
+func (s *Session) incrementSSC() {
+	for i := len(s.ssc) - 1; i >= 0; i-- {
+		s.ssc[i]++
+		if s.ssc[i] != 0 {
+			break
+		}
+	}
+}
+
+// Wrap protects a command APDU.
+func (s *Session) Wrap(cmd *iso.CAPDU) (*iso.CAPDU, error) {
+	bs := s.suite.BlockSize()
+
+	authHeader := true
+	cla, err := cmd.Cla.WithSecureMessaging(iso.SecureMessagingAuthenticated)
+	if err != nil {
+		// Further interindustry classes can not indicate authenticated headers
+		if cla, err = cmd.Cla.WithSecureMessaging(iso.SecureMessagingNoHeader); err != nil {
+			return nil, err
+		}
+
+		authHeader = false
+	}
+
+	s.incrementSSC()
+
+	var dos []byte
+
+	if len(cmd.Data) > 0 {
+		ct, err := s.suite.Encrypt(s.ssc, mac.Pad(cmd.Data, bs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt: %w", err)
+		}
+
+		var do tlv.TagValue
+		if cmd.Ins&1 == 0 {
+			do = tlv.New(TagPaddedCryptogram, paddingIndicatorISO9797M2, ct)
+		} else {
+			do = tlv.New(TagCryptogram, ct)
+		}
+
+		if dos, err = appendBER(dos, do); err != nil {
+			return nil, err
+		}
+	}
+
+	if cmd.Ne > 0 {
+		var le []byte
+		switch {
+		case cmd.Ne == iso.MaxLenRespDataExtended:
+			le = []byte{0x00, 0x00}
+		case cmd.Ne > iso.MaxLenRespDataStandard:
+			le = []byte{byte(cmd.Ne >> 8), byte(cmd.Ne)}
+		case cmd.Ne == iso.MaxLenRespDataStandard:
+			le = []byte{0x00}
+		default:
+			le = []byte{byte(cmd.Ne)}
+		}
+
+		if dos, err = appendBER(dos, tlv.New(TagLe, le)); err != nil {
+			return nil, err
+		}
+	}
+
+	macInput := append([]byte{}, s.ssc...)
+	if authHeader {
+		macInput = append(macInput, mac.Pad([]byte{byte(cla), byte(cmd.Ins), cmd.P1, cmd.P2}, bs)...)
+	}
+	macInput = append(macInput, dos...)
+
+	cc, err := s.suite.MAC(mac.Pad(macInput, bs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute MAC: %w", err)
+	}
+
+	if dos, err = appendBER(dos, tlv.New(TagCryptographicChecksum, cc)); err != nil {
+		return nil, err
+	}
+
+	ne := iso.MaxLenRespDataStandard
+	if len(dos) > iso.MaxLenCmdDataStandard || cmd.Ne > iso.MaxLenRespDataStandard {
+		ne = iso.MaxLenRespDataExtended
+	}
+
+	return &iso.CAPDU{
+		Cla:  cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: dos,
+		Ne:   ne,
+	}, nil
+}
+
+// Unwrap verifies and decrypts a protected response APDU.
+// Responses without data field are passed unmodified as cards
+// report errors in the secure messaging layer without protection.
+// The SSC is incremented for every response to stay in sync with Wrap.
+func (s *Session) Unwrap(resp *iso.RAPDU) (*iso.RAPDU, error) {
+	s.incrementSSC()
+
+	if len(resp.Data) == 0 {
+		if resp.Code().IsSuccess() {
+			return nil, fmt.Errorf("%w: no data objects in successful response", ErrMissingDO)
+		}
+
+		return resp, nil
+	}
+
+	var (
+		macInput = append([]byte{}, s.ssc...)
+		cc       []byte
+		out      = &iso.RAPDU{}
+		sw       []byte
+		ct       []byte
+		odd      bool
+	)
+
+	for buf := resp.Data; len(buf) > 0; {
+		var do tlv.TagValue
+
+		rest, err := do.UnmarshalBER(buf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data object: %w", err)
+		}
+
+		if do.Tag != TagCryptographicChecksum {
+			macInput = append(macInput, buf[:len(buf)-len(rest)]...)
+		}
+
+		switch do.Tag {
+		case TagPaddedCryptogram:
+			if len(do.Value) < 1 || do.Value[0] != paddingIndicatorISO9797M2 {
+				return nil, fmt.Errorf("%w: unsupported padding-content indicator", mac.ErrInvalidPadding)
+			}
+
+			ct = do.Value[1:]
+
+		case TagCryptogram:
+			ct = do.Value
+			odd = true
+
+		case TagPlainValue:
+			out.Data = do.Value
+
+		case TagProcessingStatus:
+			if len(do.Value) != 2 {
+				return nil, fmt.Errorf("%w: processing status", ErrInvalidLength)
+			}
+
+			sw = do.Value
+
+		case TagCryptographicChecksum:
+			cc = do.Value
+		}
+
+		buf = rest
+	}
+
+	if cc == nil {
+		return nil, fmt.Errorf("%w: cryptographic checksum", ErrMissingDO)
+	}
+
+	expCC, err := s.suite.MAC(mac.Pad(macInput, s.suite.BlockSize()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute MAC: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare(cc, expCC) != 1 {
+		return nil, ErrInvalidMAC
+	}
+
+	if ct != nil {
+		pt, err := s.suite.Decrypt(s.ssc, ct)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt: %w", err)
+		}
+
+		if out.Data, err = mac.Unpad(pt); err != nil && !odd {
+			return nil, err
+		} else if err != nil {
+			out.Data = pt
+		}
+	}
+
+	if sw != nil {
+		out.SW1, out.SW2 = sw[0], sw[1]
+	} else {
+		out.SW1, out.SW2 = resp.SW1, resp.SW2
+	}
+
+	return out, nil
+}
+
+func appendBER(buf []byte, tv tlv.TagValue) ([]byte, error) {
+	b, err := tv.MarshalBER()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, b...), nil
+}
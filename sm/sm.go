@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sm implements secure messaging as defined in ISO 7816-4 Section 10.
+package sm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid key")
+	ErrInvalidLength = errors.New("invalid length")
+	ErrInvalidMAC    = errors.New("invalid MAC")
+	ErrMissingDO     = errors.New("missing secure messaging data object")
+)
+
+var (
+	_ iso.PCSCCard     = (*Card)(nil)
+	_ iso.ContextCard  = (*Card)(nil)
+	_ iso.WrappingCard = (*Card)(nil)
+	_ iso.ATRCard      = (*Card)(nil)
+)
+
+// Wrapper protects command APDUs and verifies response APDUs
+// of a secure messaging session.
+type Wrapper interface {
+	Wrap(cmd *iso.CAPDU) (*iso.CAPDU, error)
+	Unwrap(resp *iso.RAPDU) (*iso.RAPDU, error)
+}
+
+// Card is a wrapper around iso7816.PCSCCard which
+// protects all exchanged APDUs using a Wrapper.
+//
+// As the wrapping happens on the level of the transmitted APDUs,
+// it is transparent to iso7816.Card which still handles command
+// chaining and the retrieval of remaining response data.
+// If the wrapped card is a iso7816.Card, a iso7816.Card wrapping
+// this Card inherits its logical channel, transfer limits and lock.
+type Card struct {
+	iso.PCSCCard
+	wrapper Wrapper
+}
+
+// NewCard wraps a iso7816.PCSCCard into a Card
+// which protects all APDUs using the provided Wrapper.
+func NewCard(next iso.PCSCCard, w Wrapper) *Card {
+	return &Card{
+		PCSCCard: next,
+		wrapper:  w,
+	}
+}
+
+// Wrapped returns the card wrapped by c.
+func (c *Card) Wrapped() iso.PCSCCard {
+	return c.PCSCCard
+}
+
+// ATR returns the answer-to-reset of the wrapped card.
+func (c *Card) ATR() (*iso.ATR, error) {
+	if ac, ok := c.PCSCCard.(iso.ATRCard); ok {
+		return ac.ATR()
+	}
+
+	return nil, iso.ErrATRNotAvailable
+}
+
+func (c *Card) Transmit(cmdBuf []byte) ([]byte, error) {
+	return c.TransmitContext(context.Background(), cmdBuf)
+}
+
+func (c *Card) TransmitContext(ctx context.Context, cmdBuf []byte) ([]byte, error) {
+	cmd, err := iso.ParseCAPDU(cmdBuf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CAPDU: %w", err)
+	}
+
+	wcmd, err := c.wrapper.Wrap(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap CAPDU: %w", err)
+	}
+
+	resp, err := c.exchange(ctx, wcmd)
+	if err != nil {
+		return nil, err
+	}
+
+	uresp, err := c.wrapper.Unwrap(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap RAPDU: %w", err)
+	}
+
+	return uresp.Bytes()
+}
+
+func (c *Card) BeginTransaction() error {
+	return c.BeginTransactionContext(context.Background())
+}
+
+func (c *Card) BeginTransactionContext(ctx context.Context) error {
+	if cc, ok := c.PCSCCard.(iso.ContextCard); ok {
+		return cc.BeginTransactionContext(ctx)
+	}
+
+	return c.PCSCCard.BeginTransaction()
+}
+
+// exchange transmits a protected command and collects the complete
+// protected response which might be split across several GET RESPONSE commands.
+func (c *Card) exchange(ctx context.Context, cmd *iso.CAPDU) (*iso.RAPDU, error) {
+	var data []byte
+
+	for {
+		cmdBuf, err := cmd.Bytes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize CAPDU: %w", err)
+		}
+
+		respBuf, err := c.transmit(ctx, cmdBuf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transmit CAPDU: %w", err)
+		}
+
+		resp, err := iso.ParseRAPDU(respBuf)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RAPDU: %w", err)
+		}
+
+		data = append(data, resp.Data...)
+
+		if code := resp.Code(); !code.HasMore() {
+			resp.Data = data
+			return resp, nil
+		}
+
+		// GET RESPONSE commands are not protected
+		cla, err := cmd.Cla.WithSecureMessaging(iso.SecureMessagingNone)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd = &iso.CAPDU{
+			Cla: cla.WithChaining(false),
+			Ins: iso.InsGetResponse,
+			P1:  0x00,
+			P2:  0x00,
+			Ne:  resp.Code().Available(),
+		}
+	}
+}
+
+func (c *Card) transmit(ctx context.Context, cmdBuf []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if cc, ok := c.PCSCCard.(iso.ContextCard); ok {
+		return cc.TransmitContext(ctx, cmdBuf)
+	}
+
+	return c.PCSCCard.Transmit(cmdBuf)
+}
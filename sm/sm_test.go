@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package sm_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/sm"
+	"cunicu.li/go-iso7816/test"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return b
+}
+
+func newICAO9303Card(t *testing.T) (*iso.Card, *sm.Session) {
+	require := require.New(t)
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	suite, err := sm.NewDESCipherSuite(
+		unhex("979ec13b1cbfe9dcd01ab0fed307eae5"),
+		unhex("f1cb1f1fb5adf208806b89dc579dc1f8"))
+	require.NoError(err)
+
+	sess, err := sm.NewSession(suite, unhex("887022120c06c226"))
+	require.NoError(err)
+
+	return iso.NewCard(sm.NewCard(mockCard, sess)), sess
+}
+
+func testICAO9303(t *testing.T) {
+	require := require.New(t)
+
+	card, sess := newICAO9303Card(t)
+
+	// Select EF.COM
+	_, err := card.Send(&iso.CAPDU{
+		Ins:  iso.InsSelect,
+		P1:   0x02,
+		P2:   0x0C,
+		Data: []byte{0x01, 0x1E},
+	})
+	require.NoError(err)
+
+	// Read first four bytes of EF.COM
+	resp, err := card.Send(&iso.CAPDU{
+		Ins: iso.InsReadBinary,
+		P1:  0x00,
+		P2:  0x00,
+		Ne:  4,
+	})
+	require.NoError(err)
+	require.Equal(unhex("60145f01"), resp)
+	require.Equal(unhex("887022120c06c22a"), sess.SendSequenceCounter())
+
+	err = card.Close()
+	require.NoError(err)
+}
+
+func TestICAO9303(t *testing.T) {
+	testICAO9303(t)
+}
+
+func TestGetResponse(t *testing.T) {
+	testICAO9303(t)
+}
+
+func TestInvalidMAC(t *testing.T) {
+	require := require.New(t)
+
+	card, _ := newICAO9303Card(t)
+
+	_, err := card.Send(&iso.CAPDU{
+		Ins:  iso.InsSelect,
+		P1:   0x02,
+		P2:   0x0C,
+		Data: []byte{0x01, 0x1E},
+	})
+	require.ErrorIs(err, sm.ErrInvalidMAC)
+}
+
+func TestUnprotectedError(t *testing.T) {
+	require := require.New(t)
+
+	card, sess := newICAO9303Card(t)
+
+	// Select EF.COM
+	_, err := card.Send(&iso.CAPDU{
+		Ins:  iso.InsSelect,
+		P1:   0x02,
+		P2:   0x0C,
+		Data: []byte{0x01, 0x1E},
+	})
+	require.ErrorIs(err, iso.ErrFileOrAppNotFound)
+
+	// The SSC is incremented for both the command and the unprotected response
+	require.Equal(unhex("887022120c06c228"), sess.SendSequenceCounter())
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"fmt"
+)
+
+// StatusClass is an error matching all status codes which share the same SW1 byte.
+//
+//	errors.Is(err, iso.StatusClass(0x6A)) // Matches any 6Axx status code
+//
+//nolint:errname
+type StatusClass byte
+
+func (s StatusClass) Error() string {
+	return fmt.Sprintf("status %02Xxx", byte(s))
+}
+
+// StatusError is returned if the card responds to a command with a status code
+// other than success. It unwraps to the Code returned by the card.
+type StatusError struct {
+	Code
+
+	// Header of the failed command APDU.
+	Cla    Class
+	Ins    Instruction
+	P1, P2 byte
+
+	// Data contains the response data which has been received along with the status code.
+	// Warnings (62xx and 63xx) might still return valid data.
+	Data []byte
+}
+
+func newStatusError(cmd *CAPDU, code Code, data []byte) *StatusError {
+	return &StatusError{
+		Code: code,
+		Cla:  cmd.Cla,
+		Ins:  cmd.Ins,
+		P1:   cmd.P1,
+		P2:   cmd.P2,
+		Data: data,
+	}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("command %02X%02X%02X%02X failed with status %02X%02X: %s",
+		byte(e.Cla), byte(e.Ins), e.P1, e.P2, e.Code[0], e.Code[1], e.Code.Error())
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Code
+}
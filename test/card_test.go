@@ -0,0 +1,602 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package test_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+	"cunicu.li/go-iso7816/encoding/tlv"
+	"cunicu.li/go-iso7816/filter"
+	"cunicu.li/go-iso7816/test"
+)
+
+func withMockCard(t *testing.T, cb func(t *testing.T, card *iso.Card)) {
+	require := require.New(t)
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	cb(t, iso.NewCard(mockCard))
+
+	err = mockCard.Close()
+	require.NoError(err)
+}
+
+func testData(n int) (d []byte) {
+	for i := 0; i < n; i++ {
+		d = append(d, byte(i))
+	}
+
+	return d
+}
+
+func TestSendChained(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		_, err := card.Send(&iso.CAPDU{
+			Ins:  iso.InsPutDataOdd,
+			P1:   0x3F,
+			P2:   0xFF,
+			Data: testData(300),
+		})
+		require.NoError(err)
+	})
+}
+
+func TestSendChainingNotSupported(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		_, err := card.Send(&iso.CAPDU{
+			Ins:  iso.InsPutDataOdd,
+			P1:   0x3F,
+			P2:   0xFF,
+			Data: testData(300),
+		})
+		require.ErrorIs(err, iso.ErrCommandChainingNotSupported)
+	})
+}
+
+func TestSendWrongLe(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		resp, err := card.Send(&iso.CAPDU{
+			Ins: iso.InsReadBinary,
+			P1:  0x00,
+			P2:  0x00,
+			Ne:  iso.MaxLenRespDataStandard,
+		})
+		require.NoError(err)
+		require.Equal(testData(16), resp)
+	})
+}
+
+func TestSendStatusError(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		_, err := card.Send(&iso.CAPDU{
+			Ins:  iso.InsVerify,
+			P1:   0x00,
+			P2:   0x81,
+			Data: []byte("123456"),
+		})
+		require.ErrorIs(err, iso.StatusClass(0x63))
+
+		var se *iso.StatusError
+		require.ErrorAs(err, &se)
+		require.Equal(iso.InsVerify, se.Ins)
+		require.Equal(byte(0x81), se.P2)
+
+		retries, ok := se.RetriesLeft()
+		require.True(ok)
+		require.Equal(2, retries)
+
+		// Warnings keep the returned data
+		_, err = card.Send(&iso.CAPDU{
+			Ins: iso.InsReadBinary,
+			P1:  0x00,
+			P2:  0x00,
+			Ne:  4,
+		})
+		require.ErrorIs(err, iso.ErrEOF)
+		require.ErrorAs(err, &se)
+		require.True(se.IsWarning())
+		require.Equal([]byte{0x01, 0x02}, se.Data)
+
+		_, err = card.Select([]byte{0xA0, 0x00, 0x00, 0x00, 0x01})
+		require.ErrorIs(err, iso.ErrFileOrAppNotFound)
+		require.ErrorIs(err, iso.StatusClass(0x6A))
+		require.NotErrorIs(err, iso.StatusClass(0x69))
+	})
+}
+
+func TestSelectFile(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		fcp, err := card.SelectFile(iso.FileSelection{
+			Mode:     iso.SelectMFDFOrEF,
+			Response: iso.SelectReturnNone,
+		})
+		require.NoError(err)
+		require.Nil(fcp)
+
+		fcp, err = card.SelectFile(iso.FileSelection{
+			Mode:     iso.SelectPathFromMF,
+			Response: iso.SelectReturnFCP,
+			ID:       iso.FileID(0x5015, 0x4401),
+		})
+		require.NoError(err)
+		require.Equal(uint16(0x4401), fcp.FileID)
+		require.Equal(32, fcp.Size)
+
+		_, err = card.SelectFile(iso.FileSelection{
+			Mode:       iso.SelectParentDF,
+			Occurrence: iso.SelectNext,
+		})
+		require.ErrorIs(err, iso.ErrFileOrAppNotFound)
+	})
+}
+
+func TestReadBinary(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		data, err := card.ReadBinary(0x01, 0, 0)
+		require.NoError(err)
+		require.Len(data, 300)
+		require.Equal(testData(300), data)
+
+		// Offsets beyond 32767 require the odd instruction
+		data, err = card.ReadBinary(0x00, 0x8000, 4)
+		require.NoError(err)
+		require.Equal(testData(4), data)
+	})
+}
+
+func TestWriteBinary(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		card.MaxLenCmdData = 16
+
+		err := card.UpdateBinary(0x00, 0x7FF0, testData(20))
+		require.NoError(err)
+
+		err = card.EraseBinary(0x03, 0, 0)
+		require.NoError(err)
+
+		err = card.EraseBinary(0x00, 0x8000, 0xFFFF)
+		require.NoError(err)
+	})
+}
+
+func TestRecords(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		records, err := card.ReadRecords(0x01)
+		require.NoError(err)
+		require.Equal([][]byte{{0x01, 0x02}, {0x03, 0x04}}, records)
+
+		record, err := card.ReadRecord(iso.RecordByID(0x00, 0x42, iso.RecordNextOccurrence))
+		require.NoError(err)
+		require.Equal([]byte{0xAA}, record)
+
+		err = card.AppendRecord(0x02, []byte{0x01})
+		require.NoError(err)
+
+		err = card.UpdateRecord(iso.RecordByNumber(0x00, 3), []byte{0x05})
+		require.NoError(err)
+
+		err = card.EraseRecord(0x00, 2, true)
+		require.NoError(err)
+
+		numbers, err := card.SearchRecord(0x01, 1, []byte("ab"))
+		require.NoError(err)
+		require.Equal([]byte{1, 3}, numbers)
+
+		numbers, err = card.SearchRecord(0x01, 1, []byte("ac"))
+		require.NoError(err)
+		require.Empty(numbers)
+
+		// Short EF identifiers beyond 30 can not be encoded in P2
+		_, err = card.ReadRecord(iso.RecordByNumber(31, 1))
+		require.ErrorIs(err, iso.ErrInvalidSFI)
+	})
+}
+
+func TestRecordsNotSupported(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		// The card capabilities of the ATR exclude the record number
+		_, err := card.ReadRecords(0x01)
+		require.ErrorIs(err, iso.ErrRecordNumberNotSupported)
+
+		// EF.DIR is read as a transparent EF instead
+		apps, err := card.Applications()
+		require.NoError(err)
+		require.Len(apps, 1)
+		require.Equal(iso.AidPIV, apps[0].AID)
+	})
+}
+
+func TestGetPutData(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		tvs, err := card.GetData(0x5F50)
+		require.NoError(err)
+
+		value, _, ok := tvs.Get(0x5F50)
+		require.True(ok)
+		require.Equal([]byte("ABC"), value)
+
+		tvs, err = card.GetData(0x6E)
+		require.NoError(err)
+
+		value, _, ok = tvs.GetChild(0x6E, 0x4F)
+		require.True(ok)
+		require.Equal([]byte{0x01}, value)
+
+		tvs, err = card.GetData(0x5FC105)
+		require.NoError(err)
+
+		value, _, ok = tvs.Get(0x53)
+		require.True(ok)
+		require.Equal([]byte{0x01, 0x02, 0x03}, value)
+
+		err = card.PutData(tlv.New(0x5F50, "ABC"))
+		require.NoError(err)
+
+		err = card.PutData(tlv.New(0x5FC105, byte(0x01)))
+		require.NoError(err)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		challenge, err := card.GetChallenge(8)
+		require.NoError(err)
+		require.Equal([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, challenge)
+
+		resp, err := card.InternalAuthenticate(0x00, 0x01, []byte{0x01, 0x02})
+		require.NoError(err)
+		require.Equal([]byte{0xAA, 0xBB}, resp)
+
+		err = card.ExternalAuthenticate(0x00, 0x01, []byte{0xCC})
+		require.NoError(err)
+
+		dat, err := card.GeneralAuthenticate(0x11, 0x9A, &iso.DynamicAuthenticationTemplate{
+			Challenge: []byte{0x01, 0x02},
+			Response:  []byte{},
+		})
+		require.NoError(err)
+		require.Equal([]byte{0xAA, 0xBB}, dat.Response)
+
+		ga := card.NewGeneralAuthentication(0x00, 0x00)
+		ga.Cla = iso.ClassProprietary
+		ga.Ins = iso.InsGeneralAuthenticate
+
+		dat, err = ga.Step(&iso.DynamicAuthenticationTemplate{})
+		require.NoError(err)
+		require.Equal([]byte{0x01, 0x02}, dat.Witness)
+
+		dat, err = ga.Final(&iso.DynamicAuthenticationTemplate{
+			Exponential: []byte{0xCC},
+		})
+		require.NoError(err)
+		require.Empty(dat.Other)
+	})
+}
+
+func TestSecurityOperations(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		err := card.SetSecurityEnvironment(iso.SEUsageCompute, iso.ControlReferenceTemplate{
+			Tag:        iso.TagCRTDigitalSignature,
+			Algorithm:  []byte{0x54},
+			PrivateKey: []byte{0x9C},
+		})
+		require.NoError(err)
+
+		err = card.StoreSecurityEnvironment(0x01)
+		require.NoError(err)
+
+		sig, err := card.ComputeDigitalSignature([]byte{0x01, 0x02})
+		require.NoError(err)
+		require.Equal([]byte{0xAA, 0xBB}, sig)
+
+		err = card.VerifyDigitalSignature([]byte{0x01, 0x02}, sig)
+		require.NoError(err)
+
+		hash, err := card.Hash([]byte("abc"))
+		require.NoError(err)
+		require.Equal([]byte{0xCC}, hash)
+
+		ct, err := card.Encipher([]byte{0x01})
+		require.NoError(err)
+		require.Equal([]byte{0x01, 0xDD}, ct)
+
+		pt, err := card.Decipher(ct)
+		require.NoError(err)
+		require.Equal([]byte{0x01}, pt)
+
+		err = card.VerifyCertificate([]byte{0x7F, 0x21, 0x00})
+		require.NoError(err)
+
+		err = card.RestoreSecurityEnvironment(0x01)
+		require.NoError(err)
+
+		err = card.EraseSecurityEnvironment(0x01)
+		require.NoError(err)
+	})
+}
+
+func TestApplications(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		apps, err := card.Applications()
+		require.NoError(err)
+		require.Len(apps, 1)
+		require.Equal(iso.AidYubicoOATH, apps[0].AID)
+		require.Equal("OATH", apps[0].Label)
+
+		info, err := card.CardInfo()
+		require.NoError(err)
+		require.Equal(0x20000, info.MaxLenCmdAPDU)
+		require.Equal(0x20000, info.MaxLenRespAPDU)
+		require.NotZero(info.CardCapabilities & iso.CardCapExtendedLength)
+	})
+}
+
+func TestApplicationsBinary(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		apps, err := card.Applications()
+		require.NoError(err)
+		require.Len(apps, 2)
+		require.Equal(iso.AidYubicoOATH, apps[1].AID)
+	})
+}
+
+func TestDiscover(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		// The card service data of the ATR excludes the selection by full DF name
+		applets, err := card.Discover(iso.DiscoverAll)
+		require.NoError(err)
+		require.Len(applets, 3)
+
+		require.Equal(iso.AidPIV, applets[0].AID)
+		require.Equal("PIV", applets[0].Name)
+		require.Equal(iso.DiscoverByDIR|iso.DiscoverByPartialAID, applets[0].Method)
+		require.Equal([]byte{0x61, 0x06, 0x4F, 0x04, 0x00, 0x00, 0x10, 0x00}, applets[0].Response)
+
+		require.Equal(iso.AID{0xA0, 0x00, 0x00, 0x03, 0x08, 0x00, 0x00, 0x20, 0x00}, applets[1].AID)
+		require.Empty(applets[1].Name)
+		require.Equal(iso.DiscoverByPartialAID, applets[1].Method)
+
+		require.Equal(iso.AidCardManager, applets[2].AID)
+		require.Equal("GlobalPlatform Card Manager", applets[2].Name)
+		require.Equal(iso.DiscoverByPartialAID, applets[2].Method)
+	})
+}
+
+func TestNegotiate(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		limits, err := card.Negotiate()
+		require.NoError(err)
+		require.Equal(iso.TransferLimits{
+			ExtendedLength:  true,
+			CommandChaining: true,
+			MaxLenCmdData:   2039,
+			MaxLenRespData:  2046,
+		}, limits)
+		require.Equal(limits, card.Limits())
+
+		// The expected response length is limited to the negotiated maximum
+		resp, err := card.Send(&iso.CAPDU{
+			Ins: iso.InsGetData,
+			P1:  0x01,
+			P2:  0x01,
+			Ne:  iso.MaxLenResponseDataExtended,
+		})
+		require.NoError(err)
+		require.Equal([]byte{0x01, 0x02}, resp)
+
+		// Responses are truncated if the card does not indicate remaining data
+		resp, err = card.Send(&iso.CAPDU{
+			Ins: iso.InsGetData,
+			P1:  0x01,
+			P2:  0x02,
+			Ne:  iso.MaxLenResponseDataExtended,
+		})
+		require.NoError(err)
+		require.Equal(testData(2046), resp)
+
+		_, err = card.Select(iso.AidPIV)
+		require.NoError(err)
+	})
+}
+
+func TestNegotiateProbe(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		require.Equal(iso.TransferLimits{
+			CommandChaining: true,
+			MaxLenCmdData:   iso.MaxLenCmdDataStandard,
+			MaxLenRespData:  iso.MaxLenRespDataStandard,
+		}, card.Limits())
+
+		limits, err := card.Negotiate()
+		require.NoError(err)
+		require.Equal(iso.TransferLimits{
+			ExtendedLength:  true,
+			CommandChaining: true,
+			MaxLenCmdData:   1015,
+			MaxLenRespData:  1022,
+		}, limits)
+
+		// Limits are negotiated only once
+		again, err := card.Negotiate()
+		require.NoError(err)
+		require.Equal(limits, again)
+	})
+}
+
+func TestNegotiateNoChaining(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		limits, err := card.Negotiate()
+		require.NoError(err)
+		require.False(limits.CommandChaining)
+
+		// Commands which require chaining are not sent
+		_, err = card.Send(&iso.CAPDU{
+			Ins:  iso.InsPutDataOdd,
+			P1:   0x3F,
+			P2:   0xFF,
+			Data: testData(300),
+		})
+		require.ErrorIs(err, iso.ErrCommandChainingNotSupported)
+	})
+}
+
+func TestWithCardATR(t *testing.T) {
+	test.WithCard(t, filter.Any, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		// The historical bytes are passed through the trace and mock cards
+		limits, err := card.Negotiate()
+		require.NoError(err)
+		require.Equal(iso.TransferLimits{
+			ExtendedLength:  true,
+			CommandChaining: true,
+			MaxLenCmdData:   iso.MaxLenCommandDataExtended,
+			MaxLenRespData:  iso.MaxLenResponseDataExtended,
+		}, limits)
+	})
+}
+
+func TestSendGetResponse(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		// The GET RESPONSE command must retain the logical channel
+		resp, err := card.Send(&iso.CAPDU{
+			Cla: 0x01,
+			Ins: iso.InsGetData,
+			P1:  0x00,
+			P2:  0x6E,
+			Ne:  iso.MaxLenRespDataStandard,
+		})
+		require.NoError(err)
+		require.Equal(testData(12), resp)
+	})
+}
+
+func TestSendEnvelope(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		card.UseEnvelope = true
+
+		resp, err := card.Send(&iso.CAPDU{
+			Ins: iso.InsGetData,
+			P1:  0x01,
+			P2:  0x01,
+			Ne:  512,
+		})
+		require.NoError(err)
+		require.Equal(testData(300), resp)
+	})
+}
+
+func TestOpenChannel(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		pivCard, err := card.OpenChannel()
+		require.NoError(err)
+		require.Equal(2, pivCard.Channel())
+
+		_, err = pivCard.Send(&iso.CAPDU{
+			Ins:  iso.InsSelect,
+			P1:   0x04,
+			P2:   0x00,
+			Data: iso.AidPIV,
+		})
+		require.NoError(err)
+
+		// Channels 4-19 use the further interindustry class encoding
+		pgpCard, err := card.OpenChannel()
+		require.NoError(err)
+		require.Equal(5, pgpCard.Channel())
+
+		_, err = pgpCard.Send(&iso.CAPDU{
+			Ins:  iso.InsSelect,
+			P1:   0x04,
+			P2:   0x00,
+			Data: iso.AidOpenPGP,
+		})
+		require.NoError(err)
+
+		err = pgpCard.Close()
+		require.NoError(err)
+
+		err = pivCard.Close()
+		require.NoError(err)
+	})
+}
+
+func TestSendContextCanceled(t *testing.T) {
+	withMockCard(t, func(t *testing.T, card *iso.Card) {
+		require := require.New(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := card.SendContext(ctx, &iso.CAPDU{
+			Ins: iso.InsGetChallenge,
+			Ne:  8,
+		})
+		require.ErrorIs(err, context.Canceled)
+	})
+}
+
+func TestMockATR(t *testing.T) {
+	require := require.New(t)
+
+	mockCard, err := test.NewMockCard(t, nil)
+	require.NoError(err)
+
+	atr, err := mockCard.ATR()
+	require.NoError(err)
+	require.Equal([]iso.Protocol{iso.ProtocolT1}, atr.Protocols())
+
+	hb, err := atr.HistoricalBytes()
+	require.NoError(err)
+	require.Equal([]byte("YubiKey"), hb.CardIssuer)
+
+	require.NoError(mockCard.Close())
+}
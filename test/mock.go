@@ -6,6 +6,7 @@ package test
 import (
 	"bufio"
 	"cmp"
+	"context"
 	"encoding/hex"
 	"fmt"
 	"os"
@@ -24,7 +25,11 @@ import (
 
 var ErrMalformedMockfile = fmt.Errorf("invalid mockfile")
 
-var _ iso.PCSCCard = (*MockCard)(nil)
+var (
+	_ iso.PCSCCard    = (*MockCard)(nil)
+	_ iso.ContextCard = (*MockCard)(nil)
+	_ iso.ATRCard     = (*MockCard)(nil)
+)
 
 type call struct {
 	Start, End time.Time
@@ -42,6 +47,7 @@ type MockCard struct {
 	test *testing.T
 
 	calls []call
+	meta  map[string]string
 }
 
 // NewMockCard creates a new smart card mock.
@@ -90,10 +96,18 @@ func (c *MockCard) Base() iso.PCSCCard {
 }
 
 func (c *MockCard) Transmit(cmd []byte) (resp []byte, err error) {
+	return c.TransmitContext(context.Background(), cmd)
+}
+
+func (c *MockCard) TransmitContext(ctx context.Context, cmd []byte) (resp []byte, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	if c.PCSCCard != nil {
 		start := time.Now()
 
-		resp, err = c.PCSCCard.Transmit(cmd)
+		resp, err = transmitContext(ctx, c.PCSCCard, cmd)
 
 		c.calls = append(c.calls, call{
 			Start:    start,
@@ -118,10 +132,18 @@ func (c *MockCard) Transmit(cmd []byte) (resp []byte, err error) {
 }
 
 func (c *MockCard) BeginTransaction() error {
+	return c.BeginTransactionContext(context.Background())
+}
+
+func (c *MockCard) BeginTransactionContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if c.PCSCCard != nil {
 		start := time.Now()
 
-		err := c.PCSCCard.BeginTransaction()
+		err := beginTransactionContext(ctx, c.PCSCCard)
 
 		c.calls = append(c.calls, call{
 			Start:  start,
@@ -155,6 +177,30 @@ func (c *MockCard) EndTransaction() error {
 	return args.Error(0)
 }
 
+// ATR returns the answer-to-reset of the real card or
+// the one recorded in the transcript.
+func (c *MockCard) ATR() (*iso.ATR, error) {
+	if c.PCSCCard != nil {
+		if ac, ok := c.PCSCCard.(iso.ATRCard); ok {
+			return ac.ATR()
+		}
+
+		return nil, fmt.Errorf("%w: card does not provide an ATR", ErrMalformedMockfile)
+	}
+
+	atrHex, ok := c.meta["status.atr"]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing ATR in transcript", ErrMalformedMockfile)
+	}
+
+	atr, err := hex.DecodeString(atrHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ATR: %w", err)
+	}
+
+	return iso.ParseATR(atr)
+}
+
 // LoadTranscript loads the a command transcript from
 // `mockdata/t.Name()` and configures the mock object
 // with the expected calls to Transmit(), BeginTransaction()
@@ -168,6 +214,8 @@ func (c *MockCard) LoadTranscript() error {
 
 	c.test.Logf("Mock transcript loaded from: %s", fn)
 
+	c.meta = map[string]string{}
+
 	scanner := bufio.NewScanner(f)
 	scanner.Split(bufio.ScanLines)
 
@@ -186,7 +234,10 @@ func (c *MockCard) LoadTranscript() error {
 		}
 
 		action := cols[0]
-		if action != "on" {
+		if action == "meta" && len(cols) > 2 {
+			c.meta[cols[1]] = strings.Join(cols[2:], " ")
+			continue
+		} else if action != "on" {
 			continue
 		}
 
@@ -296,6 +347,22 @@ func (c *MockCard) WriteTranscript() error {
 	return nil
 }
 
+func transmitContext(ctx context.Context, card iso.PCSCCard, cmd []byte) ([]byte, error) {
+	if cc, ok := card.(iso.ContextCard); ok {
+		return cc.TransmitContext(ctx, cmd)
+	}
+
+	return card.Transmit(cmd)
+}
+
+func beginTransactionContext(ctx context.Context, card iso.PCSCCard) error {
+	if cc, ok := card.(iso.ContextCard); ok {
+		return cc.BeginTransactionContext(ctx)
+	}
+
+	return card.BeginTransaction()
+}
+
 func inMilliseconds(d time.Duration) float64 {
 	return float64(d) / float64(time.Millisecond)
 }
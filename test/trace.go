@@ -4,6 +4,7 @@
 package test
 
 import (
+	"context"
 	"encoding/hex"
 	"log/slog"
 	"time"
@@ -11,7 +12,11 @@ import (
 	iso "cunicu.li/go-iso7816"
 )
 
-var _ iso.PCSCCard = (*TraceCard)(nil)
+var (
+	_ iso.PCSCCard    = (*TraceCard)(nil)
+	_ iso.ContextCard = (*TraceCard)(nil)
+	_ iso.ATRCard     = (*TraceCard)(nil)
+)
 
 // TraceCard is a wrapper around iso7816.PCSCCard
 // which logs a exchanged commands (APDUs) to a log/slog
@@ -36,17 +41,31 @@ func NewTraceCard(next iso.PCSCCard, logger *slog.Logger) *TraceCard {
 }
 
 func (c *TraceCard) Transmit(cmd []byte) ([]byte, error) {
-	c.logger.Info("Send ->",
+	return c.TransmitContext(context.Background(), cmd)
+}
+
+func (c *TraceCard) TransmitContext(ctx context.Context, cmd []byte) ([]byte, error) {
+	args := []any{
 		slog.Any("cmd", hex.EncodeToString(cmd)),
-		slog.Int("len", len(cmd)))
+		slog.Int("len", len(cmd)),
+	}
+
+	// Annotate the selection of applications with their names
+	if capdu, err := iso.ParseCAPDU(cmd); err == nil && capdu.Ins == iso.InsSelect && capdu.P1 == byte(iso.SelectByDFName) {
+		if name := iso.AID(capdu.Data).Name(); name != "" {
+			args = append(args, slog.String("applet", name))
+		}
+	}
+
+	c.logger.Info("Send ->", args...)
 
 	start := time.Now()
 
-	resp, err := c.PCSCCard.Transmit(cmd)
+	resp, err := transmitContext(ctx, c.PCSCCard, cmd)
 
 	end := time.Now()
 
-	args := []any{
+	args = []any{
 		slog.Duration("after", end.Sub(start)),
 	}
 
@@ -64,9 +83,13 @@ func (c *TraceCard) Transmit(cmd []byte) ([]byte, error) {
 }
 
 func (c *TraceCard) BeginTransaction() error {
+	return c.BeginTransactionContext(context.Background())
+}
+
+func (c *TraceCard) BeginTransactionContext(ctx context.Context) error {
 	c.logger.Info("BeginTransaction")
 
-	return c.PCSCCard.BeginTransaction()
+	return beginTransactionContext(ctx, c.PCSCCard)
 }
 
 func (c *TraceCard) EndTransaction() error {
@@ -75,6 +98,15 @@ func (c *TraceCard) EndTransaction() error {
 	return c.PCSCCard.EndTransaction()
 }
 
+// ATR returns the answer-to-reset of the traced card.
+func (c *TraceCard) ATR() (*iso.ATR, error) {
+	if ac, ok := c.PCSCCard.(iso.ATRCard); ok {
+		return ac.ATR()
+	}
+
+	return nil, iso.ErrATRNotAvailable
+}
+
 func (c *TraceCard) Close() error {
 	return nil
 }
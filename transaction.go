@@ -0,0 +1,330 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// CardLock serializes the access to a card.
+// It is shared by all Cards wrapping the same LockingCard, Cards bound
+// to logical channels of the same card and by the Cards of their transactions.
+// The zero value is an unlocked CardLock.
+type CardLock struct {
+	// sem is a semaphore which is held for the duration of a single
+	// command or the outermost transaction. A channel is used in
+	// favor of a mutex to support the cancellation via a context.
+	sem  chan struct{}
+	once sync.Once
+
+	// depth is the nesting depth of transactions.
+	// It is only accessed while holding sem.
+	depth int
+}
+
+// LockingCard is implemented by PCSCCards like the PC/SC driver card
+// which provide the lock shared by all Cards wrapping them.
+// Cards wrapping other PCSCCards only share their lock with Cards
+// wrapping them in turn.
+type LockingCard interface {
+	CardLock() *CardLock
+}
+
+// semaphore returns the semaphore of the lock and initializes it on first use.
+func (l *CardLock) semaphore() chan struct{} {
+	l.once.Do(func() {
+		l.sem = make(chan struct{}, 1)
+	})
+
+	return l.sem
+}
+
+// lockInitMu guards the initialization of the lock
+// of Cards which have not been created by NewCard().
+//
+//nolint:gochecknoglobals
+var lockInitMu sync.Mutex
+
+// txState indicates whether a transaction is open.
+// It is shared by the Card of the outermost Transaction, its nested
+// transactions and all Cards derived from them, e.g. Cards bound to logical
+// channels or wrapping the transaction. Those Cards skip the lock only
+// while the transaction is open.
+type txState struct {
+	open atomic.Bool
+}
+
+// heldLock is the key of a context value which indicates
+// that the lock has been acquired by the caller.
+type heldLock struct {
+	lock *CardLock
+}
+
+// lockOf returns the lock to be used by a Card wrapping c and
+// the transaction to which c belongs.
+func lockOf(c PCSCCard) (*CardLock, *txState) {
+	if w := wrappedCard(c); w != nil {
+		return w.sharedLock(), w.tx
+	}
+
+	if lc := lockingCard(c); lc != nil {
+		return lc.CardLock(), nil
+	}
+
+	return &CardLock{}, nil
+}
+
+// lockingCard returns the LockingCard which is either c itself,
+// wrapped by c via WrappingCards or the base card of c.
+// It returns nil if none of them provides a lock.
+func lockingCard(c PCSCCard) LockingCard {
+	c = unwrap(c)
+	if c == nil {
+		return nil
+	}
+
+	if lc, ok := c.(LockingCard); ok {
+		return lc
+	}
+
+	if lc, ok := c.Base().(LockingCard); ok {
+		return lc
+	}
+
+	return nil
+}
+
+// unwrap returns the card wrapped by one or more WrappingCards.
+func unwrap(c PCSCCard) PCSCCard {
+	for {
+		w, ok := c.(WrappingCard)
+		if !ok {
+			return c
+		}
+
+		c = w.Wrapped()
+	}
+}
+
+// wrappedCard returns the Card which is wrapped by c either directly
+// or via WrappingCards. It returns nil if c does not wrap a Card.
+func wrappedCard(c PCSCCard) *Card {
+	switch c := unwrap(c).(type) {
+	case *Card:
+		return c
+	case *Transaction:
+		return c.Card
+	}
+
+	return nil
+}
+
+// sharedLock returns the lock of the card
+// and initializes it for Cards which have not been created by NewCard().
+func (c *Card) sharedLock() *CardLock {
+	lockInitMu.Lock()
+	lock := c.lock
+	lockInitMu.Unlock()
+
+	if lock != nil {
+		return lock
+	}
+
+	// The lock of the wrapped card is looked up without holding lockInitMu
+	lock, _ = lockOf(c.PCSCCard)
+
+	lockInitMu.Lock()
+	defer lockInitMu.Unlock()
+
+	if c.lock == nil {
+		c.lock = lock
+	}
+
+	return c.lock
+}
+
+// base returns the underlying card skipping all Cards and Transactions
+// which share the lock of c. Those are not locked again as the lock
+// is already held by c.
+func (c *Card) base() PCSCCard {
+	lock := c.sharedLock()
+
+	for pc := c.PCSCCard; ; {
+		var next *Card
+
+		switch pc := pc.(type) {
+		case *Card:
+			next = pc
+		case *Transaction:
+			next = pc.Card
+		}
+
+		if next == nil || next.sharedLock() != lock {
+			return pc
+		}
+
+		pc = next.PCSCCard
+	}
+}
+
+// held returns true if the lock is held by the transaction to which c belongs.
+func (c *Card) held() bool {
+	return c.tx != nil && c.tx.open.Load()
+}
+
+// acquire waits until the lock of the card has been acquired.
+// The lock is not acquired again if it is already held by the
+// transaction to which c belongs or by a caller up the stack, e.g. a Card
+// wrapping c via a WrappingCard. The returned context indicates the held lock
+// to the underlying cards. The returned function releases the lock.
+func (c *Card) acquire(ctx context.Context) (_ context.Context, release func(), err error) {
+	lock := c.sharedLock()
+	if c.held() || ctx.Value(heldLock{lock}) != nil {
+		return ctx, func() {}, nil
+	}
+
+	sem := lock.semaphore()
+
+	select {
+	case sem <- struct{}{}:
+		return context.WithValue(ctx, heldLock{lock}, true), func() { <-sem }, nil
+
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// BeginTransaction starts a transaction on the card.
+func (c *Card) BeginTransaction() error {
+	return c.BeginTransactionContext(context.Background())
+}
+
+// BeginTransactionContext is like BeginTransaction but uses the provided context.
+// The context is passed to the underlying card if it implements
+// the ContextCard interface.
+// If called on the card of a Transaction, the transaction is nested and
+// only the outermost transaction is started on the underlying card.
+func (c *Card) BeginTransactionContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lock := c.sharedLock()
+	held := c.held()
+
+	if !held || lock.depth == 0 {
+		if err := c.beginTransaction(ctx); err != nil {
+			return err
+		}
+	}
+
+	if held {
+		lock.depth++
+	}
+
+	return nil
+}
+
+// EndTransaction ends a transaction on the card.
+// If called on the card of a Transaction, the transaction
+// is ended on the underlying card only by the outermost transaction.
+func (c *Card) EndTransaction() error {
+	if lock := c.sharedLock(); c.held() && lock.depth > 0 {
+		if lock.depth--; lock.depth > 0 {
+			return nil
+		}
+	}
+
+	return c.base().EndTransaction()
+}
+
+func (c *Card) beginTransaction(ctx context.Context) error {
+	base := c.base()
+	if cc, ok := base.(ContextCard); ok {
+		return cc.BeginTransactionContext(ctx)
+	}
+
+	return base.BeginTransaction()
+}
+
+// Transaction provides exclusive access to the card.
+//
+// Commands sent via the Send or Select methods of the transaction or any
+// other helper of the embedded Card are guaranteed to not be interleaved with
+// commands of other goroutines until the transaction has been closed.
+// Commands sent via the Card from which the transaction has been started block
+// until then. Hence, nested helpers must use the transaction rather than the Card.
+// Cards derived from the transaction, e.g. by opening a logical channel, are
+// part of the transaction while it is open and acquire the lock afterwards.
+type Transaction struct {
+	*Card
+
+	release func()
+	state   *txState // Only set for the outermost transaction
+}
+
+// NewTransaction starts a new transaction on the card.
+// It waits until pending commands and transactions of other goroutines
+// have been completed.
+// Transactions are re-entrant: starting a transaction on the Card of an
+// existing transaction nests it within the existing one. The underlying
+// card transaction is ended once the outermost transaction is closed.
+func (c *Card) NewTransaction() (*Transaction, error) {
+	return c.NewTransactionContext(context.Background())
+}
+
+// NewTransactionContext is like NewTransaction but uses the provided context
+// to abort waiting for the transaction.
+func (c *Card) NewTransactionContext(ctx context.Context) (*Transaction, error) {
+	ctx, release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var state *txState
+
+	tc := *c
+	if !c.held() {
+		state = &txState{}
+		state.open.Store(true)
+		tc.tx = state
+	}
+
+	if err := tc.BeginTransactionContext(ctx); err != nil {
+		if state != nil {
+			state.open.Store(false)
+		}
+
+		release()
+
+		return nil, err
+	}
+
+	return &Transaction{
+		Card:    &tc,
+		release: release,
+		state:   state,
+	}, nil
+}
+
+// Close ends the transaction.
+// Closing an already closed transaction has no effect.
+func (tx *Transaction) Close() error {
+	if tx.release == nil {
+		return nil
+	}
+
+	defer func() {
+		if tx.state != nil {
+			tx.state.open.Store(false)
+		}
+
+		tx.release()
+		tx.release = nil
+	}()
+
+	return tx.EndTransaction()
+}
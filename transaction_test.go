@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2023-2024 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package iso7816_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	iso "cunicu.li/go-iso7816"
+)
+
+// recordingCard records the transmitted commands and
+// detects concurrent calls to Transmit.
+type recordingCard struct {
+	mu       sync.Mutex
+	cmds     [][]byte
+	active   atomic.Int32
+	overlaps atomic.Int32
+	begins   atomic.Int32
+	ends     atomic.Int32
+	lock     iso.CardLock
+}
+
+func (c *recordingCard) Transmit(cmd []byte) ([]byte, error) {
+	if c.active.Add(1) > 1 {
+		c.overlaps.Add(1)
+	}
+	defer c.active.Add(-1)
+
+	time.Sleep(100 * time.Microsecond)
+
+	c.mu.Lock()
+	c.cmds = append(c.cmds, cmd)
+	c.mu.Unlock()
+
+	return []byte{0x90, 0x00}, nil
+}
+
+func (c *recordingCard) BeginTransaction() error {
+	c.begins.Add(1)
+	return nil
+}
+
+func (c *recordingCard) EndTransaction() error {
+	c.ends.Add(1)
+	return nil
+}
+
+func (c *recordingCard) Close() error {
+	return nil
+}
+
+func (c *recordingCard) Base() iso.PCSCCard {
+	return c
+}
+
+func (c *recordingCard) CardLock() *iso.CardLock {
+	return &c.lock
+}
+
+func TestConcurrentSend(t *testing.T) {
+	require := require.New(t)
+
+	rc := &recordingCard{}
+
+	// Independent wrappers of the same card must share their lock
+	cards := []*iso.Card{
+		iso.NewCard(rc),
+		iso.NewCard(rc),
+	}
+	cards = append(cards, iso.NewCard(cards[0]))
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 8)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(card *iso.Card, p1 byte) {
+			defer wg.Done()
+
+			if p1%2 == 0 {
+				_, err := card.Send(&iso.CAPDU{Ins: iso.InsGetData, P1: p1})
+				errs <- err
+
+				return
+			}
+
+			errs <- selectAndSend(card, p1)
+		}(cards[i%len(cards)], byte(i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(err)
+	}
+
+	require.Zero(rc.overlaps.Load())
+	require.Len(rc.cmds, 12)
+
+	for i, cmd := range rc.cmds {
+		if iso.Instruction(cmd[1]) == iso.InsSelect {
+			require.Less(i+1, len(rc.cmds))
+			require.Equal(iso.InsGetData, iso.Instruction(rc.cmds[i+1][1]))
+			require.Equal(cmd[5], rc.cmds[i+1][2])
+		}
+	}
+}
+
+// selectAndSend sends two commands within a transaction which must not be interleaved.
+func selectAndSend(card *iso.Card, p1 byte) error {
+	tx, err := card.NewTransaction()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Close()
+
+	if _, err := tx.Select([]byte{p1}); err != nil {
+		return err
+	}
+
+	_, err = tx.Send(&iso.CAPDU{Ins: iso.InsGetData, P1: p1})
+
+	return err
+}
+
+func TestNestedTransaction(t *testing.T) {
+	require := require.New(t)
+
+	rc := &recordingCard{}
+	card := iso.NewCard(rc)
+
+	tx, err := card.NewTransaction()
+	require.NoError(err)
+
+	nested, err := tx.NewTransaction()
+	require.NoError(err)
+
+	// Wrapping the transaction nests as well
+	wrapped, err := iso.NewCard(tx).NewTransaction()
+	require.NoError(err)
+	require.Equal(int32(1), rc.begins.Load())
+
+	_, err = wrapped.Send(&iso.CAPDU{Ins: iso.InsGetData})
+	require.NoError(err)
+
+	require.NoError(wrapped.Close())
+	require.NoError(nested.Close())
+	require.NoError(nested.Close())
+	require.Zero(rc.ends.Load())
+
+	// Other users of the card must wait for the outermost transaction
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = card.SendContext(ctx, &iso.CAPDU{Ins: iso.InsGetData})
+	require.ErrorIs(err, context.DeadlineExceeded)
+
+	require.NoError(tx.Close())
+	require.Equal(int32(1), rc.ends.Load())
+
+	_, err = card.Send(&iso.CAPDU{Ins: iso.InsGetData})
+	require.NoError(err)
+}
+
+func TestChannelAfterTransaction(t *testing.T) {
+	require := require.New(t)
+
+	rc := &recordingCard{}
+	card := iso.NewCard(rc)
+
+	tx, err := card.NewTransaction()
+	require.NoError(err)
+
+	ch, err := tx.OpenChannelNumber(1)
+	require.NoError(err)
+
+	require.NoError(tx.Close())
+
+	// The channel opened within the transaction must not skip the lock afterwards
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 8)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(card *iso.Card, p1 byte) {
+			defer wg.Done()
+
+			errs <- selectAndSend(card, p1)
+		}([]*iso.Card{card, ch}[i%2], byte(i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(err)
+	}
+
+	require.Zero(rc.overlaps.Load())
+	require.Len(rc.cmds, 17)
+
+	for i, cmd := range rc.cmds[1:] {
+		if iso.Instruction(cmd[1]) == iso.InsSelect {
+			require.Equal(iso.InsGetData, iso.Instruction(rc.cmds[i+2][1]))
+			require.Equal(cmd[5], rc.cmds[i+2][2])
+		}
+	}
+}
+
+func TestConcurrentNegotiate(t *testing.T) {
+	require := require.New(t)
+
+	rc := &recordingCard{}
+	card := iso.NewCard(rc)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 8)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(p1 byte) {
+			defer wg.Done()
+
+			if p1%2 == 0 {
+				_, err := card.Negotiate()
+				errs <- err
+
+				return
+			}
+
+			_, err := card.Send(&iso.CAPDU{Ins: iso.InsGetData, P1: p1, Ne: 1024})
+			errs <- err
+		}(byte(i))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.NoError(err)
+	}
+
+	require.Zero(rc.overlaps.Load())
+
+	// The limits are only probed once
+	require.Len(rc.cmds, 5)
+	require.True(card.Limits().ExtendedLength)
+}